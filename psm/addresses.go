@@ -0,0 +1,183 @@
+package psm
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Schema for a list of addresses as used by IP collections and security policy rules. Each entry can be an IPv4 or
+// IPv6 host, a CIDR, a range written as start-end or the keyword any. Equivalent notations and a different order
+// aren't treated as a change.
+func addressListSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validateAddress,
+		},
+		DiffSuppressFunc: suppressEquivalentAddresses,
+	}
+}
+
+// Validate an address is a host, CIDR, range or any
+func validateAddress(v interface{}, k string) ([]string, []error) {
+	if _, err := canonicalAddress(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%q: %s", k, err)}
+	}
+	return nil, nil
+}
+
+// Convert an address into the form PSM reports it in. Hosts are given a /32 or /128 mask, CIDRs are reduced to
+// their network address and IPv6 addresses are written in their shortest form.
+func canonicalAddress(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.EqualFold(value, "any") {
+		return "any", nil
+	}
+
+	if strings.Contains(value, "-") {
+		parts := strings.SplitN(value, "-", 2)
+		start := net.ParseIP(strings.TrimSpace(parts[0]))
+		end := net.ParseIP(strings.TrimSpace(parts[1]))
+		if start == nil || end == nil {
+			return "", fmt.Errorf("%q is not a valid address range, expected start-end", value)
+		}
+		if (start.To4() == nil) != (end.To4() == nil) {
+			return "", fmt.Errorf("%q mixes IPv4 and IPv6 addresses", value)
+		}
+		if bytes.Compare(start.To16(), end.To16()) > 0 {
+			return "", fmt.Errorf("%q starts after it ends", value)
+		}
+		return start.String() + "-" + end.String(), nil
+	}
+
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid CIDR", value)
+		}
+		return network.String(), nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return "", fmt.Errorf("%q must be an IP address, CIDR, range or any", value)
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
+
+// Canonicalise a list of addresses, leaving anything that can't be parsed untouched for PSM to report on
+func canonicalAddresses(addresses []string) []string {
+	result := make([]string, len(addresses))
+	for i, address := range addresses {
+		canonical, err := canonicalAddress(address)
+		if err != nil {
+			canonical = address
+		}
+		result[i] = canonical
+	}
+	return result
+}
+
+// The canonical addresses sorted and without duplicates, used to compare two lists regardless of notation and order
+func addressSet(v interface{}) []string {
+	addresses := []string{}
+	if list, ok := v.([]interface{}); ok {
+		for _, address := range list {
+			if s, ok := address.(string); ok {
+				addresses = append(addresses, s)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	result := []string{}
+	for _, address := range canonicalAddresses(addresses) {
+		if !seen[address] {
+			seen[address] = true
+			result = append(result, address)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Suppress the diff on an address list when both sides hold the same addresses. This is called for each element
+// and the length of the list, so the whole list is compared each time.
+func suppressEquivalentAddresses(k, old, new string, d *schema.ResourceData) bool {
+	list := k
+	if i := strings.LastIndex(k, "."); i > 0 {
+		list = k[:i]
+	}
+
+	o, n := d.GetChange(list)
+	return strings.Join(addressSet(o), ",") == strings.Join(addressSet(n), ",")
+}
+
+// The first and last address covered by a canonical address, with any returned as nil to match everything
+func addressBounds(address string) (net.IP, net.IP, error) {
+	canonical, err := canonicalAddress(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if canonical == "any" {
+		return nil, nil, nil
+	}
+
+	if strings.Contains(canonical, "-") {
+		parts := strings.SplitN(canonical, "-", 2)
+		return net.ParseIP(parts[0]).To16(), net.ParseIP(parts[1]).To16(), nil
+	}
+
+	_, network, _ := net.ParseCIDR(canonical)
+	start := network.IP.To16()
+	end := make(net.IP, len(start))
+	copy(end, start)
+	offset := len(start) - len(network.Mask)
+	for i, b := range network.Mask {
+		end[offset+i] |= ^b
+	}
+	return start, end, nil
+}
+
+// Check whether two addresses cover any of the same addresses. IPv4 and IPv6 addresses never overlap, other than
+// with any.
+func addressesOverlap(a, b string) bool {
+	aStart, aEnd, err := addressBounds(a)
+	if err != nil {
+		return false
+	}
+	bStart, bEnd, err := addressBounds(b)
+	if err != nil {
+		return false
+	}
+	if aStart == nil || bStart == nil {
+		return true
+	}
+	if (aStart.To4() == nil) != (bStart.To4() == nil) {
+		return false
+	}
+	return bytes.Compare(aStart, bEnd) <= 0 && bytes.Compare(bStart, aEnd) <= 0
+}
+
+// The address family of an address, either ipv4 or ipv6, or empty for any and anything that can't be parsed
+func addressFamily(address string) string {
+	start, _, err := addressBounds(address)
+	if err != nil || start == nil {
+		return ""
+	}
+	if start.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
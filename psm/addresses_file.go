@@ -0,0 +1,180 @@
+package psm
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Read a file of addresses, returning the canonical addresses along with a hash of the file content. The addresses
+// are sorted and de-duplicated, so reordering the file isn't a change and an added line shows up as a single added
+// address rather than shifting every address after it. The format is
+// taken from the file extension unless one is given: .csv files are CSV, .json files a JSON array of strings and
+// anything else plain text with one address per line and # comments.
+func readAddressesFile(path, format, column string) ([]string, string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read addresses_file: %s", err)
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = "csv"
+		case ".json":
+			format = "json"
+		default:
+			format = "text"
+		}
+	}
+
+	var entries []addressFileEntry
+	switch format {
+	case "csv":
+		entries, err = parseAddressesCSV(content, column)
+	case "json":
+		entries, err = parseAddressesJSON(content)
+	default:
+		entries, err = parseAddressesText(content)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse addresses_file %s: %s", path, err)
+	}
+
+	addresses := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		address, err := canonicalAddress(entry.value)
+		if err != nil {
+			return nil, "", fmt.Errorf("addresses_file %s %s: %s", path, entry.location, err)
+		}
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+
+	return addresses, hash, nil
+}
+
+// An address read from a file along with where it came from, so errors can point at the offending line
+type addressFileEntry struct {
+	value    string
+	location string
+}
+
+func parseAddressesText(content []byte) ([]addressFileEntry, error) {
+	entries := []addressFileEntry{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		entries = append(entries, addressFileEntry{value: text, location: fmt.Sprintf("line %d", line)})
+	}
+
+	return entries, scanner.Err()
+}
+
+// Read the addresses from one column of a CSV file. The column is either the name of a column in the header row or
+// a column number starting at 1, in which case a first row that doesn't hold an address is taken to be a header.
+func parseAddressesCSV(content []byte, column string) ([]addressFileEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	index := 0
+	byName := false
+	if column != "" {
+		if n, err := strconv.Atoi(column); err == nil {
+			if n < 1 {
+				return nil, fmt.Errorf("addresses_file_column must be 1 or more, got %d", n)
+			}
+			index = n - 1
+		} else {
+			byName = true
+		}
+	}
+
+	entries := []addressFileEntry{}
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			first = false
+			if byName {
+				index = -1
+				for i, name := range record {
+					if strings.EqualFold(strings.TrimSpace(name), column) {
+						index = i
+					}
+				}
+				if index < 0 {
+					return nil, fmt.Errorf("column %q not found in the header row", column)
+				}
+				continue
+			}
+			if index < len(record) {
+				if _, err := canonicalAddress(record[index]); err != nil {
+					continue
+				}
+			}
+		}
+
+		if index >= len(record) {
+			return nil, fmt.Errorf("line %d has no column %d", line, index+1)
+		}
+		value := strings.TrimSpace(record[index])
+		if value == "" {
+			continue
+		}
+		entries = append(entries, addressFileEntry{value: value, location: fmt.Sprintf("line %d", line)})
+	}
+
+	return entries, nil
+}
+
+func parseAddressesJSON(content []byte) ([]addressFileEntry, error) {
+	values := []string{}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of strings: %s", err)
+	}
+
+	entries := make([]addressFileEntry, len(values))
+	for i, value := range values {
+		entries[i] = addressFileEntry{value: value, location: fmt.Sprintf("entry %d", i+1)}
+	}
+
+	return entries, nil
+}
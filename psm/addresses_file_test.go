@@ -0,0 +1,196 @@
+package psm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func addressFileValues(entries []addressFileEntry) []string {
+	values := []string{}
+	for _, entry := range entries {
+		values = append(values, entry.value)
+	}
+	return values
+}
+
+func TestParseAddressesText(t *testing.T) {
+	content := "# servers\n10.1.1.1\n\n  10.1.1.2   # web\n10.2.0.0/16\n"
+
+	entries, err := parseAddressesText([]byte(content))
+	if err != nil {
+		t.Fatalf("parseAddressesText returned error: %s", err)
+	}
+
+	want := []addressFileEntry{
+		{value: "10.1.1.1", location: "line 2"},
+		{value: "10.1.1.2", location: "line 4"},
+		{value: "10.2.0.0/16", location: "line 5"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseAddressesText() = %+v, want %+v", entries, want)
+	}
+}
+
+func TestParseAddressesCSV(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		column  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "first column without header",
+			content: "10.1.1.1,web\n10.1.1.2,db\n",
+			want:    []string{"10.1.1.1", "10.1.1.2"},
+		},
+		{
+			name:    "first column with header",
+			content: "ip,name\n10.1.1.1,web\n10.1.1.2,db\n",
+			want:    []string{"10.1.1.1", "10.1.1.2"},
+		},
+		{
+			name:    "column by number with header",
+			content: "name,ip\nweb,10.1.1.1\ndb,10.1.1.2\n",
+			column:  "2",
+			want:    []string{"10.1.1.1", "10.1.1.2"},
+		},
+		{
+			name:    "column by name",
+			content: "name, IP_Address ,owner\nweb,10.1.1.1,ops\ndb,10.1.1.2,dba\n",
+			column:  "ip_address",
+			want:    []string{"10.1.1.1", "10.1.1.2"},
+		},
+		{
+			name:    "comments, blank cells and quoting",
+			content: "# export\nname,ip\nweb,\"10.1.1.1\"\nspare,\ndb, 10.1.1.2\n",
+			column:  "ip",
+			want:    []string{"10.1.1.1", "10.1.1.2"},
+		},
+		{
+			name:    "column name not found",
+			content: "name,ip\nweb,10.1.1.1\n",
+			column:  "address",
+			wantErr: `column "address" not found`,
+		},
+		{
+			name:    "column number too small",
+			content: "10.1.1.1\n",
+			column:  "0",
+			wantErr: "must be 1 or more",
+		},
+		{
+			name:    "short row",
+			content: "name,ip\nweb,10.1.1.1\ndb\n",
+			column:  "2",
+			wantErr: "line 3 has no column 2",
+		},
+	}
+
+	for _, c := range cases {
+		entries, err := parseAddressesCSV([]byte(c.content), c.column)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: parseAddressesCSV() error = %v, want %q", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseAddressesCSV() returned error: %s", c.name, err)
+			continue
+		}
+		if got := addressFileValues(entries); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: parseAddressesCSV() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseAddressesJSON(t *testing.T) {
+	entries, err := parseAddressesJSON([]byte(`["10.1.1.1", "2001:db8::/64"]`))
+	if err != nil {
+		t.Fatalf("parseAddressesJSON returned error: %s", err)
+	}
+	if got, want := addressFileValues(entries), []string{"10.1.1.1", "2001:db8::/64"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAddressesJSON() = %v, want %v", got, want)
+	}
+
+	if _, err := parseAddressesJSON([]byte(`{"addresses": ["10.1.1.1"]}`)); err == nil {
+		t.Error("parseAddressesJSON() with an object should return an error")
+	}
+}
+
+func TestReadAddressesFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cases := []struct {
+		name    string
+		path    string
+		format  string
+		column  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "text sorted and de-duplicated",
+			path: write("addresses.txt", "10.1.2.0/24\n10.1.1.1\n10.1.1.1/32\n10.1.2.7/24\n"),
+			want: []string{"10.1.1.1/32", "10.1.2.0/24"},
+		},
+		{
+			name:   "csv from extension",
+			path:   write("addresses.csv", "name,ip\nweb,10.1.1.2\ndb,10.1.1.1\n"),
+			column: "ip",
+			want:   []string{"10.1.1.1/32", "10.1.1.2/32"},
+		},
+		{
+			name: "json from extension",
+			path: write("addresses.json", `["2001:db8::1", "10.1.1.1"]`),
+			want: []string{"10.1.1.1/32", "2001:db8::1/128"},
+		},
+		{
+			name:   "format overrides extension",
+			path:   write("export.dat", "ip\n10.1.1.1\n"),
+			format: "csv",
+			want:   []string{"10.1.1.1/32"},
+		},
+		{
+			name:    "invalid address reports its line",
+			path:    write("bad.txt", "10.1.1.1\nnot-an-address\n"),
+			wantErr: "line 2",
+		},
+		{
+			name:    "missing file",
+			path:    filepath.Join(dir, "missing.txt"),
+			wantErr: "failed to read addresses_file",
+		},
+	}
+
+	for _, c := range cases {
+		addresses, hash, err := readAddressesFile(c.path, c.format, c.column)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: readAddressesFile() error = %v, want %q", c.name, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: readAddressesFile() returned error: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(addresses, c.want) {
+			t.Errorf("%s: readAddressesFile() = %v, want %v", c.name, addresses, c.want)
+		}
+		if len(hash) != 64 {
+			t.Errorf("%s: readAddressesFile() hash = %q, want a sha256 hex digest", c.name, hash)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package psm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestCanonicalAddress(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "any", want: "any"},
+		{input: "ANY", want: "any"},
+		{input: " 10.1.1.1 ", want: "10.1.1.1/32"},
+		{input: "10.1.1.0/24", want: "10.1.1.0/24"},
+		{input: "10.1.1.7/24", want: "10.1.1.0/24"},
+		{input: "10.1.1.1-10.1.1.20", want: "10.1.1.1-10.1.1.20"},
+		{input: "10.1.1.1 - 10.1.1.20", want: "10.1.1.1-10.1.1.20"},
+		{input: "2001:DB8:0:0::1", want: "2001:db8::1/128"},
+		{input: "2001:db8::1/64", want: "2001:db8::/64"},
+		{input: "2001:db8::1-2001:db8::ff", want: "2001:db8::1-2001:db8::ff"},
+		{input: "10.1.1.20-10.1.1.1", wantErr: true},
+		{input: "10.1.1.1-2001:db8::1", wantErr: true},
+		{input: "10.1.1.1-", wantErr: true},
+		{input: "10.1.1.0/33", wantErr: true},
+		{input: "10.1.1", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := canonicalAddress(c.input)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("canonicalAddress(%q) = %q, want an error", c.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("canonicalAddress(%q) returned error: %s", c.input, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("canonicalAddress(%q) = %q, want %q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestAddressSet(t *testing.T) {
+	cases := []struct {
+		name  string
+		input interface{}
+		want  []string
+	}{
+		{name: "nil", input: nil, want: []string{}},
+		{name: "empty", input: []interface{}{}, want: []string{}},
+		{
+			name:  "sorted",
+			input: []interface{}{"10.2.0.0/16", "10.1.1.1"},
+			want:  []string{"10.1.1.1/32", "10.2.0.0/16"},
+		},
+		{
+			name:  "duplicate notations",
+			input: []interface{}{"10.1.1.1", "10.1.1.1/32", "10.1.1.5/24", "10.1.1.0/24"},
+			want:  []string{"10.1.1.0/24", "10.1.1.1/32"},
+		},
+		{
+			name:  "unparseable kept",
+			input: []interface{}{"bogus", "any"},
+			want:  []string{"any", "bogus"},
+		},
+	}
+
+	for _, c := range cases {
+		if got := addressSet(c.input); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: addressSet() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSuppressEquivalentAddresses(t *testing.T) {
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"addresses": addressListSchema(),
+		},
+	}
+
+	cases := []struct {
+		name       string
+		state      map[string]string
+		config     []interface{}
+		wantChange bool
+	}{
+		{
+			name:   "same addresses",
+			state:  map[string]string{"addresses.#": "2", "addresses.0": "10.1.1.1/32", "addresses.1": "10.2.0.0/16"},
+			config: []interface{}{"10.1.1.1/32", "10.2.0.0/16"},
+		},
+		{
+			name:   "different order",
+			state:  map[string]string{"addresses.#": "2", "addresses.0": "10.1.1.1/32", "addresses.1": "10.2.0.0/16"},
+			config: []interface{}{"10.2.0.0/16", "10.1.1.1/32"},
+		},
+		{
+			name:   "different notation",
+			state:  map[string]string{"addresses.#": "2", "addresses.0": "10.1.1.1/32", "addresses.1": "2001:db8::/64"},
+			config: []interface{}{"10.1.1.1", "2001:DB8::1/64"},
+		},
+		{
+			name:       "address added",
+			state:      map[string]string{"addresses.#": "1", "addresses.0": "10.1.1.1/32"},
+			config:     []interface{}{"10.1.1.1", "10.1.1.2"},
+			wantChange: true,
+		},
+		{
+			name:       "address changed",
+			state:      map[string]string{"addresses.#": "1", "addresses.0": "10.1.1.1/32"},
+			config:     []interface{}{"10.1.1.2"},
+			wantChange: true,
+		},
+	}
+
+	for _, c := range cases {
+		state := &terraform.InstanceState{ID: "test", Attributes: c.state}
+		config := terraform.NewResourceConfigRaw(map[string]interface{}{"addresses": c.config})
+
+		diff, err := r.Diff(context.Background(), state, config, nil)
+		if err != nil {
+			t.Errorf("%s: Diff returned error: %s", c.name, err)
+			continue
+		}
+		if changed := diff != nil && len(diff.Attributes) > 0; changed != c.wantChange {
+			t.Errorf("%s: diff changed = %t, want %t (%v)", c.name, changed, c.wantChange, diff)
+		}
+	}
+}
+
+func TestAddressesOverlap(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "10.1.1.0/24", b: "10.1.1.7", want: true},
+		{a: "10.1.1.0/24", b: "10.1.2.0/24", want: false},
+		{a: "10.1.0.0/16", b: "10.1.200.0/24", want: true},
+		{a: "10.1.1.1-10.1.1.10", b: "10.1.1.10", want: true},
+		{a: "10.1.1.1-10.1.1.10", b: "10.1.1.11/32", want: false},
+		{a: "10.1.1.250-10.1.2.5", b: "10.1.2.0/24", want: true},
+		{a: "any", b: "2001:db8::1", want: true},
+		{a: "10.1.1.1", b: "any", want: true},
+		{a: "2001:db8::/32", b: "2001:db8:1::1", want: true},
+		{a: "2001:db8::/32", b: "2001:db9::1", want: false},
+		{a: "0.0.0.0/0", b: "::/0", want: false},
+		{a: "bogus", b: "10.1.1.1", want: false},
+	}
+
+	for _, c := range cases {
+		if got := addressesOverlap(c.a, c.b); got != c.want {
+			t.Errorf("addressesOverlap(%q, %q) = %t, want %t", c.a, c.b, got, c.want)
+		}
+		if got := addressesOverlap(c.b, c.a); got != c.want {
+			t.Errorf("addressesOverlap(%q, %q) = %t, want %t", c.b, c.a, got, c.want)
+		}
+	}
+}
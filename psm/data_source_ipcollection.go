@@ -0,0 +1,97 @@
+package psm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Define the Terraform data source for a single IP collection, looked up by name. The addresses of a sharded
+// collection are returned as one list, the same as the psm_ipcollection resource.
+func dataSourceIPCollection() *schema.Resource {
+	attributes := ipCollectionDataSourceAttributes()
+
+	attributes["name"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+	}
+
+	return &schema.Resource{
+		ReadContext: dataSourceIPCollectionRead,
+		Schema:      attributes,
+	}
+}
+
+// The attributes returned for an IP collection, shared between the psm_ipcollection and psm_ipcollections data sources
+func ipCollectionDataSourceAttributes() map[string]*schema.Schema {
+	stringList := func() *schema.Schema {
+		return &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+	}
+
+	return map[string]*schema.Schema{
+		"name": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"uuid": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"address_family": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"addresses":      stringList(),
+		"ip_collections": stringList(),
+		"shards":         stringList(),
+	}
+}
+
+func flattenIPCollection(collection *IPCollection, byName map[string]IPCollection) map[string]interface{} {
+	addresses, members, shards := resolveIPCollectionShards(collection, byName)
+
+	return map[string]interface{}{
+		"name":           collection.Meta.Name,
+		"uuid":           interfaceToString(collection.Meta.UUID),
+		"address_family": ipCollectionAddressFamily(collection, byName),
+		"addresses":      addresses,
+		"ip_collections": members,
+		"shards":         shards,
+	}
+}
+
+func dataSourceIPCollectionRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	// Listing the collections gets the collection and any shards in a single call
+	collections, err := listIPCollections(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	byName := ipCollectionsByName(collections)
+
+	collection, ok := byName[name]
+	if !ok {
+		return diag.Errorf("ip_collection %q not found", name)
+	}
+
+	d.SetId(interfaceToString(collection.Meta.UUID))
+	if d.Id() == "" {
+		d.SetId(name)
+	}
+
+	for k, v := range flattenIPCollection(&collection, byName) {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(fmt.Errorf("error setting %s: %s", k, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package psm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform data source listing IP collections. Collections can be filtered to the ones containing an
+// address or overlapping a CIDR, worked out locally from the collection members. Shards are reported as part of the
+// collection they belong to rather than as collections of their own.
+func dataSourceIPCollections() *schema.Resource {
+	attributes := ipCollectionDataSourceAttributes()
+	attributes["matched_addresses"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+
+	return &schema.Resource{
+		ReadContext: dataSourceIPCollectionsRead,
+		Schema: map[string]*schema.Schema{
+			"contains_ip": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPAddress,
+			},
+			"overlaps_cidr": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"include_nested": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"names": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"collections": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: attributes,
+				},
+			},
+		},
+	}
+}
+
+// All of the addresses in a collection, optionally including the addresses of the collections nested within it
+func effectiveIPCollectionAddresses(name string, byName map[string]IPCollection, nested bool, visited map[string]bool) []string {
+	if visited[name] {
+		return nil
+	}
+	visited[name] = true
+
+	collection, ok := byName[name]
+	if !ok {
+		return nil
+	}
+
+	addresses, members, _ := resolveIPCollectionShards(&collection, byName)
+	if nested {
+		for _, member := range members {
+			addresses = append(addresses, effectiveIPCollectionAddresses(member, byName, nested, visited)...)
+		}
+	}
+
+	return addresses
+}
+
+func dataSourceIPCollectionsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	containsIP := d.Get("contains_ip").(string)
+	overlapsCIDR := d.Get("overlaps_cidr").(string)
+	nested := d.Get("include_nested").(bool)
+
+	collections, err := listIPCollections(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	byName := ipCollectionsByName(collections)
+
+	// Shards are hidden behind the collection that nests them
+	shards := map[string]bool{}
+	for _, collection := range collections {
+		for _, member := range collection.Spec.IPCollections {
+			if isIPCollectionShard(collection.Meta.Name, member) {
+				shards[member] = true
+			}
+		}
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].Meta.Name < collections[j].Meta.Name
+	})
+
+	names := []string{}
+	results := []interface{}{}
+	for i := range collections {
+		collection := &collections[i]
+		if shards[collection.Meta.Name] {
+			continue
+		}
+
+		matched := []string{}
+		if containsIP != "" || overlapsCIDR != "" {
+			for _, address := range effectiveIPCollectionAddresses(collection.Meta.Name, byName, nested, map[string]bool{}) {
+				if containsIP != "" && !addressesOverlap(address, containsIP) {
+					continue
+				}
+				if overlapsCIDR != "" && !addressesOverlap(address, overlapsCIDR) {
+					continue
+				}
+				matched = append(matched, address)
+			}
+			if len(matched) == 0 {
+				continue
+			}
+		}
+
+		result := flattenIPCollection(collection, byName)
+		result["matched_addresses"] = matched
+
+		names = append(names, collection.Meta.Name)
+		results = append(results, result)
+	}
+
+	d.SetId(strings.Join([]string{"ipcollections", containsIP, overlapsCIDR, fmt.Sprint(nested)}, "/"))
+	if err := d.Set("names", names); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("collections", results); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package psm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform data source for a single network. The network can be looked up either by name or by the
+// VLAN it redirects within a VRF.
+func dataSourceNetwork() *schema.Resource {
+	attributes := networkDataSourceAttributes()
+
+	attributes["name"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ExactlyOneOf: []string{"name", "vlan_id"},
+	}
+	attributes["vlan_id"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Computed:     true,
+		ValidateFunc: validation.IntBetween(1, 4094),
+	}
+	attributes["vrf"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+	}
+
+	return &schema.Resource{
+		ReadContext: dataSourceNetworkRead,
+		Schema:      attributes,
+	}
+}
+
+// The attributes returned for a network, shared between the psm_network and psm_networks data sources
+func networkDataSourceAttributes() map[string]*schema.Schema {
+	stringList := func() *schema.Schema {
+		return &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+	}
+
+	return map[string]*schema.Schema{
+		"name": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"uuid": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"labels": {
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"type": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"vlan_id": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"vrf": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ipv4_subnet": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ipv4_gateway": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ipv6_subnet": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ipv6_gateway": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ipam_policy": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ingress_security_policies": stringList(),
+		"egress_security_policies":  stringList(),
+		"route_import_export":       routeImportExportDataSourceSchema(),
+	}
+}
+
+// PSM returns null for any unset optional string, so convert these to an empty string for the local state
+func interfaceToString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// PSM returns numbers as float64 when decoded into an interface, with null for any unset value
+func interfaceToInt(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+func flattenNetwork(network *Network) map[string]interface{} {
+	labels := map[string]interface{}{}
+	if v, ok := network.Meta.Labels.(map[string]interface{}); ok {
+		labels = v
+	}
+
+	ingress := network.Spec.IngressSecurityPolicy
+	if ingress == nil {
+		ingress = []interface{}{}
+	}
+	egress := network.Spec.EgressSecurityPolicy
+	if egress == nil {
+		egress = []interface{}{}
+	}
+
+	return map[string]interface{}{
+		"name":                      network.Meta.Name,
+		"uuid":                      network.Meta.UUID,
+		"labels":                    labels,
+		"type":                      network.Spec.Type,
+		"vlan_id":                   network.Spec.VlanID,
+		"vrf":                       network.Spec.VirtualRouter,
+		"ipv4_subnet":               interfaceToString(network.Spec.Ipv4Subnet),
+		"ipv4_gateway":              interfaceToString(network.Spec.Ipv4Gateway),
+		"ipv6_subnet":               interfaceToString(network.Spec.Ipv6Subnet),
+		"ipv6_gateway":              interfaceToString(network.Spec.Ipv6Gateway),
+		"ipam_policy":               interfaceToString(network.Spec.IpamPolicy),
+		"ingress_security_policies": ingress,
+		"egress_security_policies":  egress,
+		"route_import_export":       flattenRouteImportExport(network.Spec.RouteImportExport),
+	}
+}
+
+func dataSourceNetworkRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	network := &Network{}
+
+	if name, ok := d.GetOk("name"); ok {
+		url := config.Server + "/configs/network/v1/tenant/default/networks/" + name.(string)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return diag.Errorf("network %q not found", name.(string))
+		}
+		if resp.StatusCode != http.StatusOK {
+			return diag.Errorf("failed to read network: HTTP %s", resp.Status)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(network); err != nil {
+			return diag.FromErr(err)
+		}
+	} else {
+		// PSM has no lookup by VLAN so list the networks and find the one redirecting this VLAN in the VRF
+		vlanID := d.Get("vlan_id").(int)
+		vrf := "default"
+		if v, ok := d.GetOk("vrf"); ok {
+			vrf = v.(string)
+		}
+
+		networks, err := listNetworks(ctx, config, "")
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		matches := []string{}
+		for i := range networks {
+			if networks[i].Spec.VlanID == vlanID && networks[i].Spec.VirtualRouter == vrf {
+				*network = networks[i]
+				matches = append(matches, networks[i].Meta.Name)
+			}
+		}
+
+		if len(matches) == 0 {
+			return diag.Errorf("no network found with VLAN %d in VRF %q", vlanID, vrf)
+		}
+		if len(matches) > 1 {
+			return diag.Errorf("found %d networks with VLAN %d in VRF %q: %s", len(matches), vlanID, vrf, strings.Join(matches, ", "))
+		}
+	}
+
+	d.SetId(network.Meta.UUID)
+	for k, v := range flattenNetwork(network) {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(fmt.Errorf("error setting %s: %s", k, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package psm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform data source listing networks. All filters are optional and are combined, so a network must
+// match every filter that has been defined to be returned.
+func dataSourceNetworks() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceNetworksRead,
+		Schema: map[string]*schema.Schema{
+			"vrf": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"vlan_id_min": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(1, 4094),
+			},
+			"vlan_id_max": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(1, 4094),
+			},
+			"label_selector": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"security_policy": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"names": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"networks": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: networkDataSourceAttributes(),
+				},
+			},
+		},
+	}
+}
+
+// Check if a policy is attached to the network in either direction
+func networkHasSecurityPolicy(network *Network, policy string) bool {
+	for _, attached := range network.Spec.IngressSecurityPolicy {
+		if attached == policy {
+			return true
+		}
+	}
+	for _, attached := range network.Spec.EgressSecurityPolicy {
+		if attached == policy {
+			return true
+		}
+	}
+	return false
+}
+
+func dataSourceNetworksRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	vrf := d.Get("vrf").(string)
+	vlanMin := d.Get("vlan_id_min").(int)
+	vlanMax := d.Get("vlan_id_max").(int)
+	labelSelector := d.Get("label_selector").(string)
+	policy := d.Get("security_policy").(string)
+
+	if vlanMin != 0 && vlanMax != 0 && vlanMin > vlanMax {
+		return diag.Errorf("vlan_id_min (%d) must not be greater than vlan_id_max (%d)", vlanMin, vlanMax)
+	}
+
+	// The label selector is evaluated by PSM, the remaining filters are applied to the returned list
+	networks, err := listNetworks(ctx, config, labelSelector)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Meta.Name < networks[j].Meta.Name
+	})
+
+	names := []interface{}{}
+	results := []interface{}{}
+	for i := range networks {
+		network := &networks[i]
+		if vrf != "" && network.Spec.VirtualRouter != vrf {
+			continue
+		}
+		if vlanMin != 0 && network.Spec.VlanID < vlanMin {
+			continue
+		}
+		if vlanMax != 0 && network.Spec.VlanID > vlanMax {
+			continue
+		}
+		if policy != "" && !networkHasSecurityPolicy(network, policy) {
+			continue
+		}
+		names = append(names, network.Meta.Name)
+		results = append(results, flattenNetwork(network))
+	}
+
+	// The ID reflects the filters used so that different instances of the data source don't collide
+	d.SetId(strings.Join([]string{"networks", vrf, fmt.Sprint(vlanMin), fmt.Sprint(vlanMax), labelSelector, policy}, "/"))
+
+	if err := d.Set("names", names); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("networks", results); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
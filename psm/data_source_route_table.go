@@ -0,0 +1,119 @@
+package psm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Define the Terraform data source for the effective routes of a VRF. Unlike the psm_route_table resource this is
+// the route table PSM reports, including connected and learnt routes as well as the static routes.
+func dataSourceRouteTable() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceRouteTableRead,
+		Schema: map[string]*schema.Schema{
+			"vrf": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"routes": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"prefix": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"next_hop": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"interface": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"admin_distance": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type RouteTable struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	Meta       struct {
+		Name string      `json:"name"`
+		UUID interface{} `json:"uuid"`
+	} `json:"meta"`
+	Status struct {
+		Routes []struct {
+			Prefix        string      `json:"prefix"`
+			NextHop       interface{} `json:"next-hop"`
+			Interface     interface{} `json:"interface"`
+			Type          interface{} `json:"type"`
+			AdminDistance interface{} `json:"admin-distance"`
+		} `json:"routes"`
+	} `json:"status"`
+}
+
+func dataSourceRouteTableRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+	vrfName := d.Get("vrf").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", config.Server+"/configs/network/v1/tenant/default/route-tables/"+vrfName, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return diag.Errorf("route table for VRF %q not found", vrfName)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to read route table: HTTP %s", resp.Status)
+	}
+
+	table := &RouteTable{}
+	if err := json.NewDecoder(resp.Body).Decode(table); err != nil {
+		return diag.FromErr(err)
+	}
+
+	routes := make([]interface{}, len(table.Status.Routes))
+	for i, route := range table.Status.Routes {
+		routes[i] = map[string]interface{}{
+			"prefix":         route.Prefix,
+			"next_hop":       interfaceToString(route.NextHop),
+			"interface":      interfaceToString(route.Interface),
+			"type":           interfaceToString(route.Type),
+			"admin_distance": interfaceToInt(route.AdminDistance),
+		}
+	}
+
+	d.SetId(vrfName)
+	if err := d.Set("routes", routes); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package psm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Define the Terraform data source for a single VRF, looked up by name. Along with the VRF settings this returns
+// the names of the networks that are members of the VRF.
+func dataSourceVRF() *schema.Resource {
+	attributes := vrfDataSourceAttributes()
+
+	attributes["name"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+	}
+
+	return &schema.Resource{
+		ReadContext: dataSourceVRFRead,
+		Schema:      attributes,
+	}
+}
+
+// The attributes returned for a VRF, shared between the psm_vrf and psm_vrfs data sources
+func vrfDataSourceAttributes() map[string]*schema.Schema {
+	stringList := func() *schema.Schema {
+		return &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		}
+	}
+
+	return map[string]*schema.Schema{
+		"name": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"uuid": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"type": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"vxlan_vni": {
+			Type:     schema.TypeInt,
+			Computed: true,
+		},
+		"router_mac_address": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"default_ipam_policy": {
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"ingress_security_policies": stringList(),
+		"egress_security_policies":  stringList(),
+		"ingress_nat_policies":      stringList(),
+		"egress_nat_policies":       stringList(),
+		"ipsec_policies":            stringList(),
+		"flow_export_policies":      stringList(),
+		"route_import_export":       routeImportExportDataSourceSchema(),
+		"networks":                  stringList(),
+	}
+}
+
+// Find the names of the networks attached to each VRF, sorted so the result is stable between refreshes
+func networksByVRF(networks []Network) map[string][]interface{} {
+	names := map[string][]string{}
+	for _, network := range networks {
+		names[network.Spec.VirtualRouter] = append(names[network.Spec.VirtualRouter], network.Meta.Name)
+	}
+
+	result := map[string][]interface{}{}
+	for vrf, members := range names {
+		sort.Strings(members)
+		for _, name := range members {
+			result[vrf] = append(result[vrf], name)
+		}
+	}
+	return result
+}
+
+func flattenVRF(vrf *VRF, networks []interface{}) map[string]interface{} {
+	list := func(v []interface{}) []interface{} {
+		if v == nil {
+			return []interface{}{}
+		}
+		return v
+	}
+
+	return map[string]interface{}{
+		"name":                      vrf.Meta.Name,
+		"uuid":                      interfaceToString(vrf.Meta.UUID),
+		"type":                      vrf.Spec.Type,
+		"vxlan_vni":                 interfaceToInt(vrf.Spec.VxlanVni),
+		"router_mac_address":        interfaceToString(vrf.Spec.RouterMacAddress),
+		"default_ipam_policy":       interfaceToString(vrf.Spec.DefaultIpamPolicy),
+		"ingress_security_policies": list(vrf.Spec.IngressSecurityPolicy),
+		"egress_security_policies":  list(vrf.Spec.EgressSecurityPolicy),
+		"ingress_nat_policies":      list(vrf.Spec.IngressNatPolicy),
+		"egress_nat_policies":       list(vrf.Spec.EgressNatPolicy),
+		"ipsec_policies":            list(vrf.Spec.IpsecPolicy),
+		"flow_export_policies":      list(vrf.Spec.FlowExportPolicy),
+		"route_import_export":       flattenRouteImportExport(vrf.Spec.RouteImportExport),
+		"networks":                  list(networks),
+	}
+}
+
+func dataSourceVRFRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	vrf, diags := getVRF(ctx, config, name)
+	if diags.HasError() {
+		return diags
+	}
+
+	// The virtual router doesn't list its networks, so find the networks that point at it
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(interfaceToString(vrf.Meta.UUID))
+	if d.Id() == "" {
+		d.SetId(name)
+	}
+
+	for k, v := range flattenVRF(vrf, networksByVRF(networks)[name]) {
+		if err := d.Set(k, v); err != nil {
+			return diag.FromErr(fmt.Errorf("error setting %s: %s", k, err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package psm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Define the Terraform data source listing all of the VRFs in the tenant
+func dataSourceVRFs() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceVRFsRead,
+		Schema: map[string]*schema.Schema{
+			"names": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"vrfs": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: vrfDataSourceAttributes(),
+				},
+			},
+		},
+	}
+}
+
+func dataSourceVRFsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	vrfs, err := listVRFs(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	members := networksByVRF(networks)
+
+	sort.Slice(vrfs, func(i, j int) bool {
+		return vrfs[i].Meta.Name < vrfs[j].Meta.Name
+	})
+
+	names := make([]interface{}, len(vrfs))
+	results := make([]interface{}, len(vrfs))
+	for i := range vrfs {
+		names[i] = vrfs[i].Meta.Name
+		results[i] = flattenVRF(&vrfs[i], members[vrfs[i].Meta.Name])
+	}
+
+	d.SetId("default")
+
+	if err := d.Set("names", names); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("vrfs", results); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
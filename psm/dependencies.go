@@ -0,0 +1,354 @@
+package psm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+type WorkloadList struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	Items      []Workload  `json:"items"`
+}
+
+type NetworkSecurityPolicyList struct {
+	Kind       interface{}             `json:"kind"`
+	APIVersion interface{}             `json:"api-version"`
+	Items      []NetworkSecurityPolicy `json:"items"`
+}
+
+// Schema for the force_detach flag on objects that can be referenced by other objects. Like any delete time flag
+// it has to be applied to the state before the destroy is run.
+func forceDetachSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	}
+}
+
+// Build the diagnostic returned when a delete is blocked, listing every object that still depends on the one being
+// deleted so they can all be dealt with in one go
+func dependencyDiagnostics(kind, name string, blockers []string, hint string) diag.Diagnostics {
+	sort.Strings(blockers)
+
+	detail := fmt.Sprintf("The following objects must be removed or detached before %s %q can be deleted:\n  - %s",
+		kind, name, strings.Join(blockers, "\n  - "))
+	if hint != "" {
+		detail += "\n\n" + hint
+	}
+
+	return diag.Diagnostics{
+		{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("%s %q is still in use", kind, name),
+			Detail:   detail,
+		},
+	}
+}
+
+// Retrieve all of the workloads in the tenant with a single call
+func listWorkloads(ctx context.Context, config *Config) ([]Workload, error) {
+	client := config.Client()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", config.Server+"/configs/workload/v1/tenant/default/workloads", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list workloads: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	workloads := &WorkloadList{}
+	if err := json.NewDecoder(resp.Body).Decode(workloads); err != nil {
+		return nil, err
+	}
+
+	return workloads.Items, nil
+}
+
+// Retrieve all of the network security policies in the tenant with a single call
+func listSecurityPolicies(ctx context.Context, config *Config) ([]NetworkSecurityPolicy, error) {
+	client := config.Client()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", config.Server+"/configs/security/v1/tenant/default/networksecuritypolicies", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list security policies: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	policies := &NetworkSecurityPolicyList{}
+	if err := json.NewDecoder(resp.Body).Decode(policies); err != nil {
+		return nil, err
+	}
+
+	return policies.Items, nil
+}
+
+func putSecurityPolicy(ctx context.Context, config *Config, policy *NetworkSecurityPolicy) error {
+	client := config.Client()
+
+	jsonBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", config.Server+"/configs/security/v1/tenant/default/networksecuritypolicies/"+policy.Meta.Name, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	return nil
+}
+
+// Names of the networks that live in a VRF
+func vrfDependents(networks []Network, vrfName string) []string {
+	blockers := []string{}
+	for _, network := range networks {
+		if network.Spec.VirtualRouter == vrfName {
+			blockers = append(blockers, fmt.Sprintf("network %q", network.Meta.Name))
+		}
+	}
+	return blockers
+}
+
+// Names of the workloads with an interface in a network, either referencing it by name or by its external VLAN
+func networkDependents(workloads []Workload, networkName string, vlanID int) []string {
+	blockers := []string{}
+	for _, workload := range workloads {
+		for _, iface := range workload.Spec.Interfaces {
+			if interfaceToString(iface.Network) == networkName || (vlanID != 0 && iface.ExternalVlan == vlanID) {
+				blockers = append(blockers, fmt.Sprintf("workload %q", workload.Meta.Name))
+				break
+			}
+		}
+	}
+	return blockers
+}
+
+// Names of the security policy rules and parent collections that reference an IP collection
+func ipCollectionDependents(policies []NetworkSecurityPolicy, collections []IPCollection, collectionName string) []string {
+	blockers := []string{}
+	for _, collection := range collections {
+		if containsString(collection.Spec.IPCollections, collectionName) {
+			blockers = append(blockers, fmt.Sprintf("IP collection %q", collection.Meta.Name))
+		}
+	}
+	for _, policy := range policies {
+		for i, rule := range policy.Spec.Rules {
+			if containsString(rule.FromIPCollections, collectionName) || containsString(rule.ToIPCollections, collectionName) {
+				blockers = append(blockers, fmt.Sprintf("rule %q in security policy %q", ruleLabel(rule, i), policy.Meta.Name))
+			}
+		}
+	}
+	return blockers
+}
+
+// Names of the networks and VRFs a security policy is attached to
+func securityPolicyDependents(networks []Network, vrfs []VRF, policyName string) []string {
+	blockers := []string{}
+	for _, network := range networks {
+		if containsInterface(network.Spec.IngressSecurityPolicy, policyName) || containsInterface(network.Spec.EgressSecurityPolicy, policyName) {
+			blockers = append(blockers, fmt.Sprintf("network %q", network.Meta.Name))
+		}
+	}
+	for _, vrf := range vrfs {
+		if containsInterface(vrf.Spec.IngressSecurityPolicy, policyName) || containsInterface(vrf.Spec.EgressSecurityPolicy, policyName) {
+			blockers = append(blockers, fmt.Sprintf("VRF %q", vrf.Meta.Name))
+		}
+	}
+	return blockers
+}
+
+// Rules that would be left with nothing to match on one side if an IP collection was removed from them. PSM treats
+// an empty side as any address, so removing the collection would widen the rule.
+func ipCollectionDetachBlockers(policies []NetworkSecurityPolicy, collectionName string) []string {
+	blockers := []string{}
+	for _, policy := range policies {
+		for i, rule := range policy.Spec.Rules {
+			from, fromRemoved := removeString(rule.FromIPCollections, collectionName)
+			to, toRemoved := removeString(rule.ToIPCollections, collectionName)
+			if (fromRemoved && len(from) == 0 && len(rule.FromIPAddresses) == 0) ||
+				(toRemoved && len(to) == 0 && len(rule.ToIPAddresses) == 0) {
+				blockers = append(blockers, fmt.Sprintf("rule %q in security policy %q", ruleLabel(rule, i), policy.Meta.Name))
+			}
+		}
+	}
+	return blockers
+}
+
+// Remove every reference to an IP collection from the security policy rules and parent collections. Nothing is
+// changed if any rule only matches the collection on one side, as the rule can't be detached without widening it.
+func detachIPCollection(ctx context.Context, config *Config, policies []NetworkSecurityPolicy, collections []IPCollection, collectionName string) diag.Diagnostics {
+	if blockers := ipCollectionDetachBlockers(policies, collectionName); len(blockers) > 0 {
+		return dependencyDiagnostics("IP collection", collectionName, blockers,
+			"These rules only match this collection on their source or destination side and can't be detached "+
+				"without matching any address. Change or remove the rules before destroying the collection.")
+	}
+
+	for _, collection := range collections {
+		collection := collection
+		members, removed := removeString(collection.Spec.IPCollections, collectionName)
+		if !removed {
+			continue
+		}
+
+		log.Printf("[DEBUG] Detaching IP collection %s from IP collection %s", collectionName, collection.Meta.Name)
+		collection.Spec.IPCollections = members
+		if err := putIPCollection(ctx, config, &collection); err != nil {
+			return diag.Errorf("failed to detach IP collection %q from IP collection %q: %s", collectionName, collection.Meta.Name, err)
+		}
+	}
+
+	for _, policy := range policies {
+		policy := policy
+		changed := false
+
+		for i := range policy.Spec.Rules {
+			rule := &policy.Spec.Rules[i]
+			var fromRemoved, toRemoved bool
+			rule.FromIPCollections, fromRemoved = removeString(rule.FromIPCollections, collectionName)
+			rule.ToIPCollections, toRemoved = removeString(rule.ToIPCollections, collectionName)
+			changed = changed || fromRemoved || toRemoved
+		}
+
+		if !changed {
+			continue
+		}
+
+		log.Printf("[DEBUG] Detaching IP collection %s from security policy %s", collectionName, policy.Meta.Name)
+		if err := putSecurityPolicy(ctx, config, &policy); err != nil {
+			return diag.Errorf("failed to detach IP collection %q from security policy %q: %s", collectionName, policy.Meta.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Remove a security policy from every network and VRF it is attached to
+func detachSecurityPolicy(ctx context.Context, config *Config, networks []Network, vrfs []VRF, policyName string) diag.Diagnostics {
+	for i := range networks {
+		network := &networks[i]
+		ingress, ingressRemoved := removeInterface(network.Spec.IngressSecurityPolicy, policyName)
+		egress, egressRemoved := removeInterface(network.Spec.EgressSecurityPolicy, policyName)
+		if !ingressRemoved && !egressRemoved {
+			continue
+		}
+
+		log.Printf("[DEBUG] Detaching security policy %s from network %s", policyName, network.Meta.Name)
+		network.Spec.IngressSecurityPolicy = ingress
+		network.Spec.EgressSecurityPolicy = egress
+		if err := sendNetwork(ctx, config, "PUT", network); err != nil {
+			return diag.Errorf("failed to detach security policy %q from network %q: %s", policyName, network.Meta.Name, err)
+		}
+	}
+
+	for i := range vrfs {
+		vrf := &vrfs[i]
+		ingress, ingressRemoved := removeInterface(vrf.Spec.IngressSecurityPolicy, policyName)
+		egress, egressRemoved := removeInterface(vrf.Spec.EgressSecurityPolicy, policyName)
+		if !ingressRemoved && !egressRemoved {
+			continue
+		}
+
+		log.Printf("[DEBUG] Detaching security policy %s from VRF %s", policyName, vrf.Meta.Name)
+		vrf.Spec.IngressSecurityPolicy = ingress
+		vrf.Spec.EgressSecurityPolicy = egress
+		if diags := putVRF(ctx, config, vrf); diags.HasError() {
+			return diags
+		}
+	}
+
+	return nil
+}
+
+// PSM rules don't have to be named, so fall back to their position in the policy
+func ruleLabel(rule Rule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInterface(list []interface{}, value string) bool {
+	for _, v := range list {
+		if interfaceToString(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, value string) ([]string, bool) {
+	result := []string{}
+	for _, v := range list {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result, len(result) != len(list)
+}
+
+func removeInterface(list []interface{}, value string) ([]interface{}, bool) {
+	result := []interface{}{}
+	for _, v := range list {
+		if interfaceToString(v) != value {
+			result = append(result, v)
+		}
+	}
+	return result, len(result) != len(list)
+}
@@ -0,0 +1,233 @@
+package psm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// PSM rejects collections with too many addresses, so larger collections are split into shards of this size
+const defaultIPCollectionShardSize = 1000
+
+// Shards are named after their parent collection and numbered from 1
+func ipCollectionShardName(parent string, index int) string {
+	return fmt.Sprintf("%s-shard-%d", parent, index+1)
+}
+
+func isIPCollectionShard(parent, name string) bool {
+	suffix := strings.TrimPrefix(name, parent+"-shard-")
+	if suffix == name {
+		return false
+	}
+	n, err := strconv.Atoi(suffix)
+	return err == nil && n > 0
+}
+
+func ipCollectionsByName(collections []IPCollection) map[string]IPCollection {
+	byName := map[string]IPCollection{}
+	for _, collection := range collections {
+		byName[collection.Meta.Name] = collection
+	}
+	return byName
+}
+
+// The addresses of a sharded collection live in its shards, which are hidden from the nested collections. Returns
+// the canonical addresses including those in the shards, the nested collections and the shard names. Any nested
+// collection named like a shard is taken to be one, which is only used for reporting on collections.
+func resolveIPCollectionShards(collection *IPCollection, byName map[string]IPCollection) ([]string, []string, []string) {
+	return splitIPCollectionShards(collection, byName, func(member string) bool {
+		return isIPCollectionShard(collection.Meta.Name, member)
+	})
+}
+
+// The names in a list of shards, used to limit the shards a psm_ipcollection touches to the ones it created
+func ownedIPCollectionShards(v interface{}) map[string]bool {
+	owned := map[string]bool{}
+	if list, ok := v.([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				owned[s] = true
+			}
+		}
+	}
+	return owned
+}
+
+// Split the nested collections into shards and members, as decided by isShard, adding the shard addresses to the
+// collection's own
+func splitIPCollectionShards(collection *IPCollection, byName map[string]IPCollection, isShard func(string) bool) ([]string, []string, []string) {
+	addresses := append([]string{}, collection.Spec.Addresses...)
+	members := []string{}
+	shards := []string{}
+
+	for _, member := range collection.Spec.IPCollections {
+		if isShard(member) {
+			shards = append(shards, member)
+			addresses = append(addresses, byName[member].Spec.Addresses...)
+		} else {
+			members = append(members, member)
+		}
+	}
+
+	return canonicalAddresses(addresses), members, shards
+}
+
+// Split the addresses into shards when there are more than fit in a single collection. The addresses are sorted
+// first so the same addresses always end up in the same shards.
+func shardIPCollectionAddresses(parent string, addresses []string, size int) map[string][]string {
+	if len(addresses) <= size {
+		return nil
+	}
+
+	sorted := append([]string{}, addresses...)
+	sort.Strings(sorted)
+
+	shards := map[string][]string{}
+	for i := 0; i*size < len(sorted); i++ {
+		end := (i + 1) * size
+		if end > len(sorted) {
+			end = len(sorted)
+		}
+		shards[ipCollectionShardName(parent, i)] = sorted[i*size : end]
+	}
+
+	return shards
+}
+
+// Write the collection and its shards so PSM matches the resource. The shards are created before the parent
+// references them, and any shards no longer needed are only removed once the parent has stopped nesting them. Only
+// the shards recorded in the state belong to the resource, so a collection that merely has a shard's name is never
+// changed or deleted. If the write fails, the shards created by it are removed again rather than left unowned.
+func applyIPCollection(ctx context.Context, config *Config, d *schema.ResourceData, existing *IPCollection, collections []IPCollection) (err error) {
+	name := d.Get("name").(string)
+	family := expandIPCollectionAddressFamily(d)
+	addresses := expandIPCollectionAddresses(d)
+	members := expandIPCollectionMembers(d)
+	shards := shardIPCollectionAddresses(name, addresses, d.Get("shard_size").(int))
+
+	o, _ := d.GetChange("shards")
+	owned := ownedIPCollectionShards(o)
+
+	current := map[string]IPCollection{}
+	for _, collection := range collections {
+		if owned[collection.Meta.Name] {
+			current[collection.Meta.Name] = collection
+		} else if _, needed := shards[collection.Meta.Name]; needed {
+			return fmt.Errorf("ip_collection %q already exists and wasn't created as a shard of %q, rename or remove it before the addresses can be sharded", collection.Meta.Name, name)
+		}
+	}
+
+	shardNames := make([]string, 0, len(shards))
+	for shardName := range shards {
+		shardNames = append(shardNames, shardName)
+	}
+	sort.Slice(shardNames, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(shardNames[i], name+"-shard-"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(shardNames[j], name+"-shard-"))
+		return a < b
+	})
+
+	// If the parent can't be written, the shards it nests go back to the addresses they held before and the new
+	// ones are removed, so every address stays covered by the collection
+	created := []string{}
+	rebalanced := []IPCollection{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := range rebalanced {
+			log.Printf("[DEBUG] Restoring IP collection shard %s after a failed update", rebalanced[i].Meta.Name)
+			if restoreErr := putIPCollection(ctx, config, &rebalanced[i]); restoreErr != nil {
+				log.Printf("[WARN] Failed to restore IP collection shard %s: %s", rebalanced[i].Meta.Name, restoreErr)
+			}
+		}
+		for _, shardName := range created {
+			log.Printf("[DEBUG] Removing IP collection shard %s after a failed update", shardName)
+			if deleteErr := deleteIPCollection(ctx, config, shardName); deleteErr != nil {
+				log.Printf("[WARN] Failed to remove IP collection shard %s: %s", shardName, deleteErr)
+			}
+		}
+	}()
+
+	for _, shardName := range shardNames {
+		shard, exists := current[shardName]
+		if exists && reflect.DeepEqual(shard.Spec.Addresses, shards[shardName]) && shard.Spec.AddressFamily == family {
+			continue
+		}
+
+		shard.Meta.Name = shardName
+		shard.Spec.AddressFamily = family
+		shard.Spec.Addresses = shards[shardName]
+		shard.Spec.IPCollections = []string{}
+
+		if exists {
+			log.Printf("[DEBUG] Rebalancing IP collection shard %s", shardName)
+			if err := putIPCollection(ctx, config, &shard); err != nil {
+				return err
+			}
+
+			// The shard has a new resource version once it has been written, so the restore goes without one
+			previous := current[shardName]
+			previous.Meta.ResourceVersion = nil
+			rebalanced = append(rebalanced, previous)
+		} else {
+			log.Printf("[DEBUG] Creating IP collection shard %s", shardName)
+			if err := createIPCollection(ctx, config, &shard); err != nil {
+				return err
+			}
+			created = append(created, shardName)
+		}
+	}
+
+	parent := existing
+	if parent == nil {
+		parent = &IPCollection{}
+		parent.Meta.Name = name
+	}
+	parent.Spec.AddressFamily = family
+	if len(shards) > 0 {
+		parent.Spec.Addresses = []string{}
+		parent.Spec.IPCollections = append(members, shardNames...)
+	} else {
+		parent.Spec.Addresses = addresses
+		parent.Spec.IPCollections = members
+	}
+
+	if existing == nil {
+		if err := createIPCollection(ctx, config, parent); err != nil {
+			return err
+		}
+	} else if err := putIPCollection(ctx, config, parent); err != nil {
+		return err
+	}
+	created = nil
+	rebalanced = nil
+
+	// The parent now nests the new shards, so record them before removing the ones that are no longer needed. A
+	// shard that fails to be removed stays recorded so the removal is retried.
+	recorded := append([]string{}, shardNames...)
+	for shardName := range current {
+		if _, ok := shards[shardName]; !ok {
+			recorded = append(recorded, shardName)
+		}
+	}
+	d.Set("shards", recorded)
+
+	for shardName := range current {
+		if _, ok := shards[shardName]; !ok {
+			log.Printf("[DEBUG] Removing IP collection shard %s", shardName)
+			if err := deleteIPCollection(ctx, config, shardName); err != nil {
+				return err
+			}
+		}
+	}
+	d.Set("shards", shardNames)
+
+	return nil
+}
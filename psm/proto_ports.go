@@ -0,0 +1,228 @@
+package psm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// ProtoPort is a protocol and port match on a security policy rule. PSM holds the ports as a comma separated
+// string of ports and ranges, and the ICMP type and code as type/code.
+type ProtoPort struct {
+	Protocol string `json:"protocol"`
+	Ports    string `json:"ports,omitempty"`
+}
+
+// Schema for the proto_ports blocks of a rule. tcp and udp take a list of ports and ranges, icmp an optional type
+// and code, and any matches every protocol.
+func protoPortsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"protocol": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"tcp", "udp", "icmp", "any"}, false),
+				},
+				"ports": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validatePortRange,
+					},
+				},
+				"icmp_type": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      -1,
+					ValidateFunc: validation.IntBetween(0, 255),
+				},
+				"icmp_code": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      -1,
+					ValidateFunc: validation.IntBetween(0, 255),
+				},
+			},
+		},
+	}
+}
+
+// Schema for the proto_ports read back from PSM into the computed spec
+func protoPortsComputedSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"protocol": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"ports": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"icmp_type": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+				"icmp_code": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+			},
+		},
+	}
+}
+
+// Validate a port is either a single port or a range written as start-end
+func validatePortRange(v interface{}, k string) ([]string, []error) {
+	if _, _, err := parsePortRange(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%q: %s", k, err)}
+	}
+	return nil, nil
+}
+
+func parsePortRange(value string) (int, int, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "-", 2)
+
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || start < 1 || start > 65535 {
+		return 0, 0, fmt.Errorf("%q must be a port between 1 and 65535 or a range such as 8000-8080", value)
+	}
+	if len(parts) == 1 {
+		return start, start, nil
+	}
+
+	end, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || end < 1 || end > 65535 {
+		return 0, 0, fmt.Errorf("%q must be a port between 1 and 65535 or a range such as 8000-8080", value)
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("%q starts after it ends", value)
+	}
+	return start, end, nil
+}
+
+// Check the proto_ports of a rule only use the settings that apply to their protocol
+func validateRuleProtoPorts(rule map[string]interface{}, label string) error {
+	protoPorts, _ := rule["proto_ports"].([]interface{})
+	if len(protoPorts) == 0 {
+		return nil
+	}
+
+	if apps, _ := rule["apps"].([]interface{}); len(apps) > 0 {
+		return fmt.Errorf("rule %q can match on either apps or proto_ports, not both", label)
+	}
+
+	for _, v := range protoPorts {
+		protoPort, _ := v.(map[string]interface{})
+		if protoPort == nil {
+			continue
+		}
+		protocol, _ := protoPort["protocol"].(string)
+		ports, _ := protoPort["ports"].([]interface{})
+		icmpType, _ := protoPort["icmp_type"].(int)
+		icmpCode, _ := protoPort["icmp_code"].(int)
+
+		if len(ports) > 0 && protocol != "tcp" && protocol != "udp" {
+			return fmt.Errorf("rule %q: ports can only be used with tcp and udp, not %s", label, protocol)
+		}
+		if (icmpType >= 0 || icmpCode >= 0) && protocol != "icmp" {
+			return fmt.Errorf("rule %q: icmp_type and icmp_code can only be used with icmp, not %s", label, protocol)
+		}
+		if icmpCode >= 0 && icmpType < 0 {
+			return fmt.Errorf("rule %q: icmp_code needs an icmp_type", label)
+		}
+	}
+
+	return nil
+}
+
+// Build the PSM proto-ports of a rule from its proto_ports blocks
+func expandProtoPorts(v interface{}) []ProtoPort {
+	list, _ := v.([]interface{})
+	protoPorts := []ProtoPort{}
+
+	for _, item := range list {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		protoPort := ProtoPort{Protocol: block["protocol"].(string)}
+
+		switch protoPort.Protocol {
+		case "tcp", "udp":
+			ports := []string{}
+			for _, port := range block["ports"].([]interface{}) {
+				start, end, err := parsePortRange(port.(string))
+				if err != nil {
+					// Already rejected by validation, so pass it through for PSM to report
+					ports = append(ports, port.(string))
+				} else if start == end {
+					ports = append(ports, strconv.Itoa(start))
+				} else {
+					ports = append(ports, fmt.Sprintf("%d-%d", start, end))
+				}
+			}
+			protoPort.Ports = strings.Join(ports, ",")
+		case "icmp":
+			if icmpType := block["icmp_type"].(int); icmpType >= 0 {
+				protoPort.Ports = strconv.Itoa(icmpType)
+				if icmpCode := block["icmp_code"].(int); icmpCode >= 0 {
+					protoPort.Ports += "/" + strconv.Itoa(icmpCode)
+				}
+			}
+		}
+
+		protoPorts = append(protoPorts, protoPort)
+	}
+
+	return protoPorts
+}
+
+// Convert the PSM proto-ports of a rule back into proto_ports blocks
+func flattenProtoPorts(protoPorts []ProtoPort) []interface{} {
+	result := make([]interface{}, len(protoPorts))
+
+	for i, protoPort := range protoPorts {
+		protocol := strings.ToLower(protoPort.Protocol)
+		ports := []interface{}{}
+		icmpType, icmpCode := -1, -1
+
+		if protocol == "icmp" {
+			if protoPort.Ports != "" {
+				parts := strings.SplitN(protoPort.Ports, "/", 2)
+				if n, err := strconv.Atoi(parts[0]); err == nil {
+					icmpType = n
+				}
+				if len(parts) == 2 {
+					if n, err := strconv.Atoi(parts[1]); err == nil {
+						icmpCode = n
+					}
+				}
+			}
+		} else if protoPort.Ports != "" {
+			for _, port := range strings.Split(protoPort.Ports, ",") {
+				ports = append(ports, strings.TrimSpace(port))
+			}
+		}
+
+		result[i] = map[string]interface{}{
+			"protocol":  protocol,
+			"ports":     ports,
+			"icmp_type": icmpType,
+			"icmp_code": icmpCode,
+		}
+	}
+
+	return result
+}
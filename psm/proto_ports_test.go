@@ -0,0 +1,152 @@
+package psm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	cases := []struct {
+		input      string
+		start, end int
+		wantErr    bool
+	}{
+		{input: "443", start: 443, end: 443},
+		{input: " 22 ", start: 22, end: 22},
+		{input: "8000-8080", start: 8000, end: 8080},
+		{input: "8000 - 8080", start: 8000, end: 8080},
+		{input: "1-65535", start: 1, end: 65535},
+		{input: "80-80", start: 80, end: 80},
+		{input: "0", wantErr: true},
+		{input: "65536", wantErr: true},
+		{input: "8080-8000", wantErr: true},
+		{input: "1-65536", wantErr: true},
+		{input: "80,443", wantErr: true},
+		{input: "http", wantErr: true},
+		{input: "80-", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, c := range cases {
+		start, end, err := parsePortRange(c.input)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parsePortRange(%q) = %d, %d, want an error", c.input, start, end)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePortRange(%q) returned error: %s", c.input, err)
+			continue
+		}
+		if start != c.start || end != c.end {
+			t.Errorf("parsePortRange(%q) = %d, %d, want %d, %d", c.input, start, end, c.start, c.end)
+		}
+	}
+}
+
+func TestExpandProtoPorts(t *testing.T) {
+	block := func(protocol string, ports []interface{}, icmpType, icmpCode int) interface{} {
+		return map[string]interface{}{
+			"protocol":  protocol,
+			"ports":     ports,
+			"icmp_type": icmpType,
+			"icmp_code": icmpCode,
+		}
+	}
+
+	cases := []struct {
+		name  string
+		input interface{}
+		want  []ProtoPort
+	}{
+		{name: "none", input: []interface{}{}, want: []ProtoPort{}},
+		{
+			name:  "tcp port list and ranges",
+			input: []interface{}{block("tcp", []interface{}{"80", " 443", "8000 - 8080"}, -1, -1)},
+			want:  []ProtoPort{{Protocol: "tcp", Ports: "80,443,8000-8080"}},
+		},
+		{
+			name:  "udp without ports",
+			input: []interface{}{block("udp", []interface{}{}, -1, -1)},
+			want:  []ProtoPort{{Protocol: "udp"}},
+		},
+		{
+			name:  "icmp type and code",
+			input: []interface{}{block("icmp", []interface{}{}, 8, 0)},
+			want:  []ProtoPort{{Protocol: "icmp", Ports: "8/0"}},
+		},
+		{
+			name:  "icmp type only",
+			input: []interface{}{block("icmp", []interface{}{}, 3, -1)},
+			want:  []ProtoPort{{Protocol: "icmp", Ports: "3"}},
+		},
+		{
+			name:  "icmp any type",
+			input: []interface{}{block("icmp", []interface{}{}, -1, -1)},
+			want:  []ProtoPort{{Protocol: "icmp"}},
+		},
+		{
+			name: "several protocols",
+			input: []interface{}{
+				block("tcp", []interface{}{"22"}, -1, -1),
+				nil,
+				block("any", []interface{}{}, -1, -1),
+			},
+			want: []ProtoPort{{Protocol: "tcp", Ports: "22"}, {Protocol: "any"}},
+		},
+	}
+
+	for _, c := range cases {
+		got := expandProtoPorts(c.input)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: expandProtoPorts() = %+v, want %+v", c.name, got, c.want)
+			continue
+		}
+
+		// Reading the proto-ports back has to give the same blocks, less any whitespace
+		for i, protoPort := range flattenProtoPorts(got) {
+			again := expandProtoPorts([]interface{}{protoPort})
+			if !reflect.DeepEqual(again[0], got[i]) {
+				t.Errorf("%s: flattenProtoPorts() round trip = %+v, want %+v", c.name, again[0], got[i])
+			}
+		}
+	}
+}
+
+func TestValidateRuleProtoPorts(t *testing.T) {
+	rule := func(apps []interface{}, protocol string, ports []interface{}, icmpType, icmpCode int) map[string]interface{} {
+		return map[string]interface{}{
+			"apps": apps,
+			"proto_ports": []interface{}{map[string]interface{}{
+				"protocol":  protocol,
+				"ports":     ports,
+				"icmp_type": icmpType,
+				"icmp_code": icmpCode,
+			}},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		rule    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "tcp with ports", rule: rule(nil, "tcp", []interface{}{"443"}, -1, -1)},
+		{name: "icmp with type and code", rule: rule(nil, "icmp", nil, 8, 0)},
+		{name: "any", rule: rule(nil, "any", nil, -1, -1)},
+		{name: "apps only", rule: map[string]interface{}{"apps": []interface{}{"SSH"}}},
+		{name: "apps and proto_ports", rule: rule([]interface{}{"SSH"}, "tcp", nil, -1, -1), wantErr: true},
+		{name: "icmp with ports", rule: rule(nil, "icmp", []interface{}{"80"}, -1, -1), wantErr: true},
+		{name: "any with ports", rule: rule(nil, "any", []interface{}{"80"}, -1, -1), wantErr: true},
+		{name: "tcp with icmp type", rule: rule(nil, "tcp", nil, 8, -1), wantErr: true},
+		{name: "icmp code without type", rule: rule(nil, "icmp", nil, -1, 0), wantErr: true},
+	}
+
+	for _, c := range cases {
+		err := validateRuleProtoPorts(c.rule, c.name)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: validateRuleProtoPorts() error = %v, want error %t", c.name, err, c.wantErr)
+		}
+	}
+}
@@ -13,11 +13,27 @@ type SwitchName string
 func Provider() *schema.Provider {
 	return &schema.Provider{
 		ResourcesMap: map[string]*schema.Resource{
-			"psm_network":      resourceNetwork(),
-			"psm_workload":     resourceWorkload(),
-			"psm_rules":        resourceRules(),
-			"psm_vrf":          resourceVRF(),
-			"psm_ipcollection": resourceIPCollection(),
+			"psm_network":             resourceNetwork(),
+			"psm_workload":            resourceWorkload(),
+			"psm_rules":               resourceRules(),
+			"psm_vrf":                 resourceVRF(),
+			"psm_ipcollection":        resourceIPCollection(),
+			"psm_ipam_policy":         resourceIPAMPolicy(),
+			"psm_network_set":         resourceNetworkSet(),
+			"psm_flow_export_policy":  resourceFlowExportPolicy(),
+			"psm_nat_policy":          resourceNATPolicy(),
+			"psm_ipsec_policy":        resourceIPsecPolicy(),
+			"psm_route_table":         resourceRouteTable(),
+			"psm_ipcollection_member": resourceIPCollectionMember(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"psm_network":       dataSourceNetwork(),
+			"psm_networks":      dataSourceNetworks(),
+			"psm_vrf":           dataSourceVRF(),
+			"psm_vrfs":          dataSourceVRFs(),
+			"psm_route_table":   dataSourceRouteTable(),
+			"psm_ipcollection":  dataSourceIPCollection(),
+			"psm_ipcollections": dataSourceIPCollections(),
 		},
 		Schema: map[string]*schema.Schema{
 			"user": &schema.Schema{
@@ -0,0 +1,439 @@
+package psm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform resource schema for flow export policies. A flow export policy sends IPFIX records for the
+// flows matching its rules to one or more collectors, and is attached to a VRF with flow_export_policies.
+func resourceFlowExportPolicy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceFlowExportPolicyCreate,
+		ReadContext:   resourceFlowExportPolicyRead,
+		UpdateContext: resourceFlowExportPolicyUpdate,
+		DeleteContext: resourceFlowExportPolicyDelete,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"interval": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "10s",
+				ValidateFunc: validateDurationBetween(time.Second, 24*time.Hour),
+			},
+			"template_interval": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "5m",
+				ValidateFunc: validateDurationBetween(time.Minute, 30*time.Minute),
+			},
+			"format": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "ipfix",
+				ValidateFunc: validation.StringInSlice([]string{"ipfix"}, false),
+			},
+			"collector": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				MaxItems: 4,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"destination": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPAddress,
+						},
+						"port": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      2055,
+							ValidateFunc: validation.IsPortNumber,
+						},
+						"transport": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "udp",
+							ValidateFunc: validation.StringInSlice([]string{"udp"}, false),
+						},
+						"vrf": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "default",
+						},
+						"gateway": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsIPAddress),
+						},
+					},
+				},
+			},
+			"match_rule": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"source_ip_addresses": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"destination_ip_addresses": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"apps": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type FlowExportPolicy struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	Meta       struct {
+		Name            string      `json:"name"`
+		Tenant          string      `json:"tenant"`
+		Namespace       interface{} `json:"namespace"`
+		GenerationID    interface{} `json:"generation-id"`
+		ResourceVersion interface{} `json:"resource-version"`
+		UUID            interface{} `json:"uuid"`
+		Labels          interface{} `json:"labels"`
+		SelfLink        interface{} `json:"self-link"`
+		DisplayName     interface{} `json:"display-name"`
+	} `json:"meta"`
+	Spec struct {
+		Interval         string                `json:"interval"`
+		TemplateInterval string                `json:"template-interval"`
+		Format           string                `json:"format"`
+		MatchRules       []FlowExportMatchRule `json:"match-rules"`
+		Exports          []FlowExportTarget    `json:"exports"`
+	} `json:"spec"`
+}
+
+type FlowExportMatchRule struct {
+	Source               *FlowExportMatchSelector `json:"source,omitempty"`
+	Destination          *FlowExportMatchSelector `json:"destination,omitempty"`
+	AppProtocolSelectors *FlowExportAppSelector   `json:"app-protocol-selectors,omitempty"`
+}
+
+type FlowExportMatchSelector struct {
+	IPAddresses []string `json:"ip-addresses"`
+}
+
+type FlowExportAppSelector struct {
+	Applications []string `json:"applications"`
+}
+
+type FlowExportTarget struct {
+	Destination   string `json:"destination"`
+	Transport     string `json:"transport"`
+	Gateway       string `json:"gateway,omitempty"`
+	VirtualRouter string `json:"virtual-router,omitempty"`
+}
+
+// Validate a duration such as 10s or 5m falls within the range PSM accepts
+func validateDurationBetween(min, max time.Duration) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) ([]string, []error) {
+		duration, err := time.ParseDuration(v.(string))
+		if err != nil {
+			return nil, []error{fmt.Errorf("%q must be a duration such as 10s or 5m, got %q", k, v.(string))}
+		}
+		if duration < min || duration > max {
+			return nil, []error{fmt.Errorf("%q must be between %s and %s, got %s", k, min, max, duration)}
+		}
+		return nil, nil
+	}
+}
+
+// Build the flow export policy spec from the resource definition
+func expandFlowExportPolicy(d *schema.ResourceData, policy *FlowExportPolicy) {
+	policy.Spec.Interval = d.Get("interval").(string)
+	policy.Spec.TemplateInterval = d.Get("template_interval").(string)
+	policy.Spec.Format = d.Get("format").(string)
+
+	policy.Spec.Exports = []FlowExportTarget{}
+	for _, v := range d.Get("collector").([]interface{}) {
+		collector := v.(map[string]interface{})
+		policy.Spec.Exports = append(policy.Spec.Exports, FlowExportTarget{
+			Destination:   collector["destination"].(string),
+			Transport:     fmt.Sprintf("%s/%d", collector["transport"].(string), collector["port"].(int)),
+			Gateway:       collector["gateway"].(string),
+			VirtualRouter: collector["vrf"].(string),
+		})
+	}
+
+	policy.Spec.MatchRules = []FlowExportMatchRule{}
+	for _, v := range d.Get("match_rule").([]interface{}) {
+		rule := FlowExportMatchRule{}
+		if v != nil {
+			ruleMap := v.(map[string]interface{})
+			if addresses := convertToStringSlice(ruleMap["source_ip_addresses"].([]interface{})); len(addresses) > 0 {
+				rule.Source = &FlowExportMatchSelector{IPAddresses: addresses}
+			}
+			if addresses := convertToStringSlice(ruleMap["destination_ip_addresses"].([]interface{})); len(addresses) > 0 {
+				rule.Destination = &FlowExportMatchSelector{IPAddresses: addresses}
+			}
+			if apps := convertToStringSlice(ruleMap["apps"].([]interface{})); len(apps) > 0 {
+				rule.AppProtocolSelectors = &FlowExportAppSelector{Applications: apps}
+			}
+		}
+		policy.Spec.MatchRules = append(policy.Spec.MatchRules, rule)
+	}
+}
+
+func flattenFlowExportPolicy(d *schema.ResourceData, policy *FlowExportPolicy) error {
+	collectors := make([]interface{}, len(policy.Spec.Exports))
+	for i, export := range policy.Spec.Exports {
+		// The transport is sent as protocol/port, for example udp/2055
+		transport, port := export.Transport, 0
+		if parts := strings.SplitN(export.Transport, "/", 2); len(parts) == 2 {
+			transport = strings.ToLower(parts[0])
+			port, _ = strconv.Atoi(parts[1])
+		}
+		collectors[i] = map[string]interface{}{
+			"destination": export.Destination,
+			"port":        port,
+			"transport":   transport,
+			"vrf":         export.VirtualRouter,
+			"gateway":     export.Gateway,
+		}
+	}
+
+	rules := make([]interface{}, len(policy.Spec.MatchRules))
+	for i, rule := range policy.Spec.MatchRules {
+		ruleMap := map[string]interface{}{
+			"source_ip_addresses":      []string{},
+			"destination_ip_addresses": []string{},
+			"apps":                     []string{},
+		}
+		if rule.Source != nil {
+			ruleMap["source_ip_addresses"] = rule.Source.IPAddresses
+		}
+		if rule.Destination != nil {
+			ruleMap["destination_ip_addresses"] = rule.Destination.IPAddresses
+		}
+		if rule.AppProtocolSelectors != nil {
+			ruleMap["apps"] = rule.AppProtocolSelectors.Applications
+		}
+		rules[i] = ruleMap
+	}
+
+	d.Set("name", policy.Meta.Name)
+	d.Set("interval", policy.Spec.Interval)
+	d.Set("template_interval", policy.Spec.TemplateInterval)
+	d.Set("format", policy.Spec.Format)
+	if err := d.Set("collector", collectors); err != nil {
+		return err
+	}
+	return d.Set("match_rule", rules)
+}
+
+func resourceFlowExportPolicyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	policy := &FlowExportPolicy{}
+	policy.Meta.Name = d.Get("name").(string)
+	policy.Meta.Tenant = "default"
+	expandFlowExportPolicy(d, policy)
+
+	jsonBytes, err := json.Marshal(policy)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	log.Printf("[DEBUG] Creating flow export policy with name: %s", policy.Meta.Name)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.Server+"/configs/monitoring/v1/tenant/default/flowExportPolicy", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] Error when creating flow export policy: %s", err)
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		errMsg := fmt.Sprintf("failed to create flow export policy: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "Flow export policy creation failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	responseBody := &FlowExportPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(responseBody); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(responseBody.Meta.UUID.(string))
+
+	return append(diag.Diagnostics{}, resourceFlowExportPolicyRead(ctx, d, m)...)
+}
+
+func resourceFlowExportPolicyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/monitoring/v1/tenant/default/flowExportPolicy/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		d.SetId("")
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to read flow export policy: HTTP %s", resp.Status)
+	}
+
+	policy := &FlowExportPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policy); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := flattenFlowExportPolicy(d, policy); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceFlowExportPolicyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/monitoring/v1/tenant/default/flowExportPolicy/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to get current flow export policy state: HTTP %s", resp.Status)
+	}
+
+	policyCurrent := &FlowExportPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policyCurrent); err != nil {
+		return diag.FromErr(err)
+	}
+
+	expandFlowExportPolicy(d, policyCurrent)
+
+	jsonBytes, err := json.Marshal(policyCurrent)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	respUpdate, err := client.Do(reqUpdate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer respUpdate.Body.Close()
+
+	if respUpdate.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(respUpdate.Body)
+		errMsg := fmt.Sprintf("failed to update flow export policy: HTTP %d %s: %s", respUpdate.StatusCode, respUpdate.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "Flow export policy update failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	return resourceFlowExportPolicyRead(ctx, d, m)
+}
+
+func resourceFlowExportPolicyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/monitoring/v1/tenant/default/flowExportPolicy/" + d.Get("name").(string)
+
+	log.Printf("[DEBUG] Deleting flow export policy with name: %s", d.Get("name").(string))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return diag.Errorf("failed to delete flow export policy: HTTP %s", resp.Status)
+	}
+
+	d.SetId("")
+
+	return nil
+}
@@ -4,31 +4,80 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 // Define the Terraform resource schema for ip_collections
 func resourceIPCollection() *schema.Resource {
+	// The addresses are computed when they are loaded from addresses_file, so the diff shows the addresses added
+	// and removed from the file
+	addresses := addressListSchema()
+	addresses.Computed = true
+	addresses.ConflictsWith = []string{"addresses_file"}
+
 	return &schema.Resource{
 		CreateContext: resourceIPCollectionCreate,
 		ReadContext:   resourceIPCollectionRead,
+		UpdateContext: resourceIPCollectionUpdate,
 		DeleteContext: resourceIPCollectionDelete,
+		CustomizeDiff: resourceIPCollectionCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
 				ForceNew: true,
 			},
-			"addresses": {
+			"addresses": addresses,
+			"addresses_file": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"addresses_file_format": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"text", "csv", "json"}, false),
+			},
+			"addresses_file_column": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"addresses_file_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"shard_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      defaultIPCollectionShardSize,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"shards": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ip_collections": {
 				Type:     schema.TypeList,
 				Optional: true,
 				Elem:     &schema.Schema{Type: schema.TypeString},
-				ForceNew: true,
 			},
+			"address_family": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"ipv4", "ipv6"}, false),
+			},
+			// Remove the collection from any security policy rules and collections referencing it when it is destroyed
+			"force_detach": forceDetachSchema(),
 		},
 	}
 }
@@ -38,117 +87,540 @@ type IPCollection struct {
 	Kind       interface{} `json:"kind"`
 	APIVersion interface{} `json:"api-version"`
 	Meta       struct {
-		Name   string `json:"name"`
-		Tenant string `json:"tenant"`
+		Name            string      `json:"name"`
+		Tenant          string      `json:"tenant"`
+		Namespace       interface{} `json:"namespace"`
+		GenerationID    interface{} `json:"generation-id"`
+		ResourceVersion interface{} `json:"resource-version"`
+		UUID            interface{} `json:"uuid"`
+		Labels          interface{} `json:"labels"`
+		SelfLink        interface{} `json:"self-link"`
 	} `json:"meta"`
 	Spec struct {
-		Addresses []string `json:"addresses"`
+		AddressFamily string   `json:"address-family,omitempty"`
+		Addresses     []string `json:"addresses"`
+		IPCollections []string `json:"ipcollections"`
 	} `json:"spec"`
 }
 
+type IPCollectionList struct {
+	Kind       interface{}    `json:"kind"`
+	APIVersion interface{}    `json:"api-version"`
+	Items      []IPCollection `json:"items"`
+}
+
+func resourceIPCollectionCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if err := customizeIPCollectionAddresses(d); err != nil {
+		return err
+	}
+	if d.Id() != "" && d.HasChanges("addresses", "shard_size") {
+		if err := d.SetNewComputed("shards"); err != nil {
+			return err
+		}
+	}
+	if err := validateIPCollectionAddressFamily(d); err != nil {
+		return err
+	}
+	return validateIPCollectionNesting(ctx, d, m)
+}
+
+// Load the addresses from addresses_file when it is used. The file is read on every plan so any edit shows up as
+// the addresses added and removed, with the content hash recording which version of the file was applied.
+func customizeIPCollectionAddresses(d *schema.ResourceDiff) error {
+	if !d.NewValueKnown("addresses_file") {
+		if err := d.SetNewComputed("addresses_file_hash"); err != nil {
+			return err
+		}
+		return d.SetNewComputed("addresses")
+	}
+
+	path := d.Get("addresses_file").(string)
+	if path == "" {
+		if d.Get("addresses_file_hash").(string) != "" {
+			if err := d.SetNew("addresses_file_hash", ""); err != nil {
+				return err
+			}
+		}
+
+		// As addresses is computed, removing it from the configuration needs to empty the collection explicitly
+		config := d.GetRawConfig()
+		if !config.IsNull() && config.IsKnown() && config.GetAttr("addresses").IsNull() && len(d.Get("addresses").([]interface{})) > 0 {
+			return d.SetNew("addresses", []interface{}{})
+		}
+		return nil
+	}
+
+	addresses, hash, err := readAddressesFile(path, d.Get("addresses_file_format").(string), d.Get("addresses_file_column").(string))
+	if err != nil {
+		return err
+	}
+
+	if hash != d.Get("addresses_file_hash").(string) {
+		if err := d.SetNew("addresses_file_hash", hash); err != nil {
+			return err
+		}
+	}
+
+	// Only replace the addresses when they differ, as the diff suppression of the list isn't applied to SetNew
+	list := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		list[i] = address
+	}
+	if strings.Join(addressSet(d.Get("addresses")), ",") != strings.Join(addressSet(list), ",") {
+		return d.SetNew("addresses", list)
+	}
+
+	return nil
+}
+
+// Check every address belongs to the address family of the collection when one is set
+func validateIPCollectionAddressFamily(d *schema.ResourceDiff) error {
+	family, _ := d.GetOk("address_family")
+	if !d.NewValueKnown("address_family") || !d.NewValueKnown("addresses") || family == nil || family.(string) == "" {
+		return nil
+	}
+
+	for _, v := range d.Get("addresses").([]interface{}) {
+		address, _ := v.(string)
+		if f := addressFamily(address); f != "" && f != family.(string) {
+			return fmt.Errorf("address %q is not an %s address, but the collection's address_family is %s", address, f, family.(string))
+		}
+	}
+
+	return nil
+}
+
+// Work out the address family of an existing collection, either the one it was given or the one all of its
+// addresses share. Collections mixing families, or with no addresses, don't have one.
+func ipCollectionAddressFamily(collection *IPCollection, byName map[string]IPCollection) string {
+	if collection.Spec.AddressFamily != "" {
+		return strings.ToLower(collection.Spec.AddressFamily)
+	}
+
+	addresses, _, _ := resolveIPCollectionShards(collection, byName)
+	family := ""
+	for _, address := range addresses {
+		f := addressFamily(address)
+		if f == "" {
+			continue
+		}
+		if family != "" && f != family {
+			return ""
+		}
+		family = f
+	}
+	return family
+}
+
+// Nested collections can't form a loop, so check the planned members against the collections already in PSM.
+// Collections in the same plan that name each other as plain strings don't exist yet, so the check is repeated
+// before each collection is written. Nested collections also have to share the collection's address family.
+func validateIPCollectionNesting(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("ip_collections") || !d.NewValueKnown("name") {
+		return nil
+	}
+	if d.Id() != "" && !d.HasChanges("ip_collections", "address_family") {
+		return nil
+	}
+
+	name := d.Get("name").(string)
+	members := []string{}
+	for _, v := range d.Get("ip_collections").([]interface{}) {
+		member, _ := v.(string)
+		if member == name {
+			return fmt.Errorf("ip_collection %q can't contain itself", name)
+		}
+		members = append(members, member)
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	collections, err := listIPCollections(ctx, m.(*Config))
+	if err != nil {
+		return err
+	}
+
+	if err := checkIPCollectionCycle(name, members, collections); err != nil {
+		return err
+	}
+
+	// An address family that isn't configured is only known once the collection has been read back from PSM
+	if !d.NewValueKnown("address_family") {
+		return nil
+	}
+	if family := d.Get("address_family").(string); family != "" {
+		byName := ipCollectionsByName(collections)
+		for _, member := range members {
+			collection, ok := byName[member]
+			if !ok {
+				continue
+			}
+			if f := ipCollectionAddressFamily(&collection, byName); f != "" && f != family {
+				return fmt.Errorf("nested ip_collection %q is %s, but the collection's address_family is %s", member, f, family)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Check nesting members in the named collection doesn't loop back to it through the collections in PSM
+func checkIPCollectionCycle(name string, members []string, collections []IPCollection) error {
+	graph := map[string][]string{}
+	for _, collection := range collections {
+		graph[collection.Meta.Name] = collection.Spec.IPCollections
+	}
+	graph[name] = members
+
+	if cycle := findIPCollectionCycle(graph, name); cycle != nil {
+		return fmt.Errorf("ip_collections creates a loop between nested collections: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// Walk the nested collections from start, returning the path back to start if there is one
+func findIPCollectionCycle(graph map[string][]string, start string) []string {
+	visited := map[string]bool{}
+
+	var walk func(name string, path []string) []string
+	walk = func(name string, path []string) []string {
+		for _, member := range graph[name] {
+			if member == start {
+				return append(path, member)
+			}
+			if visited[member] {
+				continue
+			}
+			visited[member] = true
+			if cycle := walk(member, append(path, member)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	return walk(start, []string{start})
+}
+
+// Retrieve all of the IP collections in the tenant with a single call
+func listIPCollections(ctx context.Context, config *Config) ([]IPCollection, error) {
+	client := config.Client()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", config.Server+"/configs/network/v1/tenant/default/ipcollections", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list ip_collections: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	collections := &IPCollectionList{}
+	if err := json.NewDecoder(resp.Body).Decode(collections); err != nil {
+		return nil, err
+	}
+
+	return collections.Items, nil
+}
+
 // Implement the Create method for ip_collections
 func resourceIPCollectionCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	config := m.(*Config)
-	client := config.Client()
 
-	ipCollection := &IPCollection{}
-	ipCollection.Meta.Name = d.Get("name").(string)
-	if addresses, ok := d.GetOk("addresses"); ok {
-		for _, addr := range addresses.([]interface{}) {
-			ipCollection.Spec.Addresses = append(ipCollection.Spec.Addresses, addr.(string))
-		}
+	// List the collections so shard names already in use are caught before anything is created
+	collections, err := listIPCollections(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Another collection in the same apply may have been created since the plan, nesting this one
+	if err := checkIPCollectionCycle(d.Get("name").(string), expandIPCollectionMembers(d), collections); err != nil {
+		return diag.FromErr(err)
 	}
 
+	if err := applyIPCollection(ctx, config, d, nil, collections); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(d.Get("name").(string))
+
+	return resourceIPCollectionRead(ctx, d, m)
+}
+
+func createIPCollection(ctx context.Context, config *Config, ipCollection *IPCollection) error {
+	client := config.Client()
+
 	jsonBytes, err := json.Marshal(ipCollection)
 	if err != nil {
-		return diag.FromErr(err)
+		return err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", config.Server+"/configs/network/v1/tenant/default/ipcollections", bytes.NewBuffer(jsonBytes))
 	if err != nil {
-		return diag.FromErr(err)
+		return err
 	}
 
 	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return diag.FromErr(err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return diag.Errorf("failed to create ip_collection: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to create ip_collection: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
 	}
 
-	responseIPCollection := &IPCollection{}
-	if err := json.NewDecoder(bytes.NewBuffer(bodyBytes)).Decode(responseIPCollection); err != nil {
-		return diag.FromErr(err)
+	return nil
+}
+
+func deleteIPCollection(ctx context.Context, config *Config, name string) error {
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipcollections/" + name
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
 	}
 
-	d.SetId(responseIPCollection.Meta.Name)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	return resourceIPCollectionRead(ctx, d, m)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete ip_collection: HTTP %s", resp.Status)
+	}
+
+	return nil
 }
 
-// Implement the Read method for ip_collections
-func resourceIPCollectionRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	config := m.(*Config)
+func expandIPCollectionAddresses(d *schema.ResourceData) []string {
+	addresses := []string{}
+	for _, addr := range d.Get("addresses").([]interface{}) {
+		addresses = append(addresses, addr.(string))
+	}
+	return canonicalAddresses(addresses)
+}
+
+// PSM names the address families IPv4 and IPv6
+func expandIPCollectionAddressFamily(d *schema.ResourceData) string {
+	switch d.Get("address_family").(string) {
+	case "ipv4":
+		return "IPv4"
+	case "ipv6":
+		return "IPv6"
+	}
+	return ""
+}
+
+func expandIPCollectionMembers(d *schema.ResourceData) []string {
+	members := []string{}
+	for _, member := range d.Get("ip_collections").([]interface{}) {
+		members = append(members, member.(string))
+	}
+	return members
+}
+
+// Retrieve an IP collection from PSM, returning nil if it doesn't exist
+func getIPCollection(ctx context.Context, config *Config, name string) (*IPCollection, error) {
 	client := config.Client()
 
-	url := config.Server + "/configs/network/v1/tenant/default/ipcollections/" + d.Get("name").(string)
+	url := config.Server + "/configs/network/v1/tenant/default/ipcollections/" + name
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
 	}
 
 	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return diag.FromErr(err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return diag.Errorf("failed to read ip_collection: HTTP %s", resp.Status)
+		return nil, fmt.Errorf("failed to read ip_collection: HTTP %s", resp.Status)
 	}
 
 	ipCollection := &IPCollection{}
 	if err := json.NewDecoder(resp.Body).Decode(ipCollection); err != nil {
+		return nil, err
+	}
+
+	return ipCollection, nil
+}
+
+// Returned when PSM rejects an update because the collection has changed since it was read
+var errIPCollectionConflict = errors.New("ip_collection was modified by someone else")
+
+// Replace an existing IP collection. The collection is sent back with the meta PSM returned so the object, and
+// every rule referencing it, is preserved. The resource-version in the meta makes PSM reject the update if the
+// collection has changed since it was read.
+func putIPCollection(ctx context.Context, config *Config, ipCollection *IPCollection) error {
+	client := config.Client()
+
+	jsonBytes, err := json.Marshal(ipCollection)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", config.Server+"/configs/network/v1/tenant/default/ipcollections/"+ipCollection.Meta.Name, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusPreconditionFailed {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: HTTP %d %s: %s", errIPCollectionConflict, resp.StatusCode, resp.Status, bodyBytes)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to update ip_collection: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	return nil
+}
+
+// Implement the Read method for ip_collections
+func resourceIPCollectionRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	name := d.Get("name").(string)
+
+	ipCollection, err := getIPCollection(ctx, config, name)
+	if err != nil {
 		return diag.FromErr(err)
 	}
+	if ipCollection == nil {
+		log.Printf("[WARN] IP collection %s not found, removing from state", name)
+		d.SetId("")
+		return nil
+	}
+
+	// Only the shards this resource created are treated as shards, anything else nested is a member collection.
+	// Shards that failed to be removed are kept until they are, even though the collection no longer nests them.
+	owned := ownedIPCollectionShards(d.Get("shards"))
+	var byName map[string]IPCollection
+	if len(owned) > 0 {
+		collections, err := listIPCollections(ctx, config)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		byName = ipCollectionsByName(collections)
+	}
+
+	addresses, members, shards := splitIPCollectionShards(ipCollection, byName, func(member string) bool {
+		return owned[member]
+	})
+	for _, shard := range d.Get("shards").([]interface{}) {
+		if _, exists := byName[shard.(string)]; exists && !containsString(shards, shard.(string)) {
+			shards = append(shards, shard.(string))
+		}
+	}
 
 	d.Set("name", ipCollection.Meta.Name)
-	d.Set("addresses", ipCollection.Spec.Addresses)
+	d.Set("addresses", addresses)
+	d.Set("ip_collections", members)
+	d.Set("shards", shards)
+	d.Set("address_family", strings.ToLower(ipCollection.Spec.AddressFamily))
 
 	return nil
 }
 
+// Implement the Update method for ip_collections
+func resourceIPCollectionUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	if d.HasChanges("addresses", "ip_collections", "shard_size", "address_family") {
+		ipCollection, err := getIPCollection(ctx, config, d.Get("name").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if ipCollection == nil {
+			return diag.Errorf("ip_collection %q no longer exists", d.Get("name").(string))
+		}
+
+		collections, err := listIPCollections(ctx, config)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		if err := checkIPCollectionCycle(d.Get("name").(string), expandIPCollectionMembers(d), collections); err != nil {
+			return diag.FromErr(err)
+		}
+
+		if err := applyIPCollection(ctx, config, d, ipCollection, collections); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return resourceIPCollectionRead(ctx, d, m)
+}
+
 // Implement the Delete method for ip_collections
 func resourceIPCollectionDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	config := m.(*Config)
-	client := config.Client()
+	name := d.Get("name").(string)
 
-	url := config.Server + "/configs/network/v1/tenant/default/ipcollections/" + d.Get("name").(string)
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	// Rules referencing a deleted collection would be left dangling, so either detach it or report what uses it
+	policies, err := listSecurityPolicies(ctx, config)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-
-	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
-
-	resp, err := client.Do(req)
+	collections, err := listIPCollections(ctx, config)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	defer resp.Body.Close()
+	if blockers := ipCollectionDependents(policies, collections, name); len(blockers) > 0 {
+		if !d.Get("force_detach").(bool) {
+			return dependencyDiagnostics("IP collection", name, blockers,
+				"Set force_detach = true and apply before destroying to remove the references automatically. "+
+					"Rules that only match this collection on one side still have to be changed first.")
+		}
+		if diags := detachIPCollection(ctx, config, policies, collections, name); diags.HasError() {
+			return diags
+		}
+	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		return diag.Errorf("failed to delete ip_collection: HTTP %s", resp.Status)
+	if err := deleteIPCollection(ctx, config, name); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// The shards can only go once the parent no longer nests them. Only the shards this resource created are
+	// removed, in case anything else happens to be named like one.
+	for _, shard := range d.Get("shards").([]interface{}) {
+		if err := deleteIPCollection(ctx, config, shard.(string)); err != nil {
+			return diag.FromErr(err)
+		}
 	}
 
 	d.SetId("")
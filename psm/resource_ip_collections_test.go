@@ -0,0 +1,88 @@
+package psm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckIPCollectionCycle(t *testing.T) {
+	collection := func(name string, members ...string) IPCollection {
+		c := IPCollection{}
+		c.Meta.Name = name
+		c.Spec.IPCollections = members
+		return c
+	}
+
+	cases := []struct {
+		name        string
+		collection  string
+		members     []string
+		collections []IPCollection
+		wantErr     string
+	}{
+		{
+			name:        "no nesting",
+			collection:  "a",
+			collections: []IPCollection{collection("b")},
+		},
+		{
+			name:        "chain",
+			collection:  "a",
+			members:     []string{"b"},
+			collections: []IPCollection{collection("b", "c"), collection("c")},
+		},
+		{
+			name:        "shared member",
+			collection:  "a",
+			members:     []string{"b", "c"},
+			collections: []IPCollection{collection("b", "d"), collection("c", "d"), collection("d")},
+		},
+		{
+			name:        "direct loop",
+			collection:  "a",
+			members:     []string{"b"},
+			collections: []IPCollection{collection("b", "a")},
+			wantErr:     "a -> b -> a",
+		},
+		{
+			name:        "loop through several collections",
+			collection:  "a",
+			members:     []string{"b"},
+			collections: []IPCollection{collection("b", "c"), collection("c", "a")},
+			wantErr:     "a -> b -> c -> a",
+		},
+		{
+			name:        "existing members replaced by the planned ones",
+			collection:  "a",
+			members:     []string{"c"},
+			collections: []IPCollection{collection("a", "b"), collection("b", "a"), collection("c")},
+		},
+		{
+			// Collections in the same plan naming each other as plain strings: when a is planned b doesn't exist
+			// yet, so the loop is only caught once a has been written and b is checked against it
+			name:       "same plan, first collection",
+			collection: "a",
+			members:    []string{"b"},
+		},
+		{
+			name:        "same plan, second collection",
+			collection:  "b",
+			members:     []string{"a"},
+			collections: []IPCollection{collection("a", "b")},
+			wantErr:     "b -> a -> b",
+		},
+	}
+
+	for _, c := range cases {
+		err := checkIPCollectionCycle(c.collection, c.members, c.collections)
+		if c.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: checkIPCollectionCycle() returned error: %s", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: checkIPCollectionCycle() error = %v, want %q", c.name, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package psm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform resource schema for IPAM policies. Currently PSM only supports DHCP relay policies which
+// define the DHCP servers that requests from workloads behind a DSC are relayed towards.
+func resourceIPAMPolicy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceIPAMPolicyCreate,
+		ReadContext:   resourceIPAMPolicyRead,
+		UpdateContext: resourceIPAMPolicyUpdate,
+		DeleteContext: resourceIPAMPolicyDelete,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"dhcp_server": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"ip_address": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPAddress,
+						},
+						"vrf": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "default",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type IPAMPolicy struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	Meta       struct {
+		Name            string      `json:"name"`
+		Tenant          string      `json:"tenant"`
+		Namespace       interface{} `json:"namespace"`
+		GenerationID    interface{} `json:"generation-id"`
+		ResourceVersion interface{} `json:"resource-version"`
+		UUID            interface{} `json:"uuid"`
+		Labels          interface{} `json:"labels"`
+		SelfLink        interface{} `json:"self-link"`
+		DisplayName     interface{} `json:"display-name"`
+	} `json:"meta"`
+	Spec struct {
+		Type      string `json:"type"`
+		DHCPRelay struct {
+			Servers []DHCPServer `json:"servers"`
+		} `json:"dhcp-relay"`
+	} `json:"spec"`
+}
+
+type DHCPServer struct {
+	IPAddress     string `json:"ip-address"`
+	VirtualRouter string `json:"virtual-router"`
+}
+
+// Build the list of DHCP relay servers from the dhcp_server blocks in the resource definition
+func expandDHCPServers(d *schema.ResourceData) []DHCPServer {
+	servers := []DHCPServer{}
+	for _, v := range d.Get("dhcp_server").([]interface{}) {
+		server := v.(map[string]interface{})
+		servers = append(servers, DHCPServer{
+			IPAddress:     server["ip_address"].(string),
+			VirtualRouter: server["vrf"].(string),
+		})
+	}
+	return servers
+}
+
+func resourceIPAMPolicyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	policy := &IPAMPolicy{}
+	policy.Meta.Name = d.Get("name").(string)
+	policy.Meta.Tenant = "default"
+	policy.Spec.Type = "dhcp-relay"
+	policy.Spec.DHCPRelay.Servers = expandDHCPServers(d)
+
+	jsonBytes, err := json.Marshal(policy)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	log.Printf("[DEBUG] Creating IPAM policy with name: %s", policy.Meta.Name)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.Server+"/configs/network/v1/tenant/default/ipam-policies", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] Error when creating IPAM policy: %s", err)
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		errMsg := fmt.Sprintf("failed to create IPAM policy: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "IPAM policy creation failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	responseBody := &IPAMPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(responseBody); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(responseBody.Meta.UUID.(string))
+
+	return append(diag.Diagnostics{}, resourceIPAMPolicyRead(ctx, d, m)...)
+}
+
+func resourceIPAMPolicyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipam-policies/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		d.SetId("")
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to read IPAM policy: HTTP %s", resp.Status)
+	}
+
+	policy := &IPAMPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policy); err != nil {
+		return diag.FromErr(err)
+	}
+
+	servers := make([]interface{}, len(policy.Spec.DHCPRelay.Servers))
+	for i, server := range policy.Spec.DHCPRelay.Servers {
+		servers[i] = map[string]interface{}{
+			"ip_address": server.IPAddress,
+			"vrf":        server.VirtualRouter,
+		}
+	}
+
+	d.Set("name", policy.Meta.Name)
+	if err := d.Set("dhcp_server", servers); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceIPAMPolicyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipam-policies/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to get current IPAM policy state: HTTP %s", resp.Status)
+	}
+
+	policyCurrent := &IPAMPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policyCurrent); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if d.HasChange("dhcp_server") {
+		policyCurrent.Spec.DHCPRelay.Servers = expandDHCPServers(d)
+	}
+
+	jsonBytes, err := json.Marshal(policyCurrent)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	respUpdate, err := client.Do(reqUpdate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer respUpdate.Body.Close()
+
+	if respUpdate.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(respUpdate.Body)
+		errMsg := fmt.Sprintf("failed to update IPAM policy: HTTP %d %s: %s", respUpdate.StatusCode, respUpdate.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "IPAM policy update failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	return resourceIPAMPolicyRead(ctx, d, m)
+}
+
+func resourceIPAMPolicyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipam-policies/" + d.Get("name").(string)
+
+	log.Printf("[DEBUG] Deleting IPAM policy with name: %s", d.Get("name").(string))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return diag.Errorf("failed to delete IPAM policy: HTTP %s", resp.Status)
+	}
+
+	d.SetId("")
+
+	return nil
+}
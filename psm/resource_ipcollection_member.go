@@ -0,0 +1,244 @@
+package psm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Define the Terraform resource schema for a single address in an IP collection owned by someone else. Only the
+// address is managed, so several teams can add their own addresses to a shared collection.
+func resourceIPCollectionMember() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceIPCollectionMemberCreate,
+		ReadContext:   resourceIPCollectionMemberRead,
+		DeleteContext: resourceIPCollectionMemberDelete,
+		Schema: map[string]*schema.Schema{
+			"collection": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"address": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateAddress,
+				StateFunc: func(v interface{}) string {
+					return canonicalAddresses([]string{v.(string)})[0]
+				},
+			},
+		},
+	}
+}
+
+// Returned when the collection being modified doesn't exist
+var errIPCollectionNotFound = errors.New("ip_collection not found")
+
+// Number of times a change is retried when another writer updates the collection between the read and the write
+const ipCollectionMemberAttempts = 5
+
+// Read the collection, apply the change and write it back. PSM rejects the write if the collection changed since
+// it was read, in which case the whole read-modify-write is retried so no one else's change is lost.
+func modifyIPCollection(ctx context.Context, config *Config, name string, modify func(*IPCollection) bool) error {
+	for attempt := 1; ; attempt++ {
+		ipCollection, err := getIPCollection(ctx, config, name)
+		if err != nil {
+			return err
+		}
+		if ipCollection == nil {
+			return fmt.Errorf("%w: %q", errIPCollectionNotFound, name)
+		}
+
+		if !modify(ipCollection) {
+			return nil
+		}
+
+		err = putIPCollection(ctx, config, ipCollection)
+		if err == nil || !errors.Is(err, errIPCollectionConflict) || attempt == ipCollectionMemberAttempts {
+			return err
+		}
+
+		log.Printf("[DEBUG] ip_collection %s changed while being updated, retrying (attempt %d)", name, attempt)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+func resourceIPCollectionMemberCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	name := d.Get("collection").(string)
+	address := canonicalAddresses([]string{d.Get("address").(string)})[0]
+
+	log.Printf("[DEBUG] Adding %s to ip_collection %s", address, name)
+
+	// An address already in the collection, or in one of its shards, belongs to whoever added it, so it isn't
+	// taken over by this resource and removed again when it is destroyed
+	var lookupErr error
+	present := false
+	err := modifyIPCollection(ctx, config, name, func(ipCollection *IPCollection) bool {
+		addresses, err := ipCollectionMemberAddresses(ctx, config, ipCollection)
+		if err != nil {
+			lookupErr = err
+			return false
+		}
+		for _, existing := range addresses {
+			if existing == address {
+				present = true
+				return false
+			}
+		}
+		ipCollection.Spec.Addresses = append(ipCollection.Spec.Addresses, address)
+		return true
+	})
+	if err == nil {
+		err = lookupErr
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if present {
+		return diag.Diagnostics{
+			diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("%s is already in ip_collection %s", address, name),
+				Detail:   "The address was added outside of this resource. Remove it from the collection before managing it with psm_ipcollection_member, or leave the resource out.",
+			},
+		}
+	}
+
+	d.SetId(name + "/" + address)
+
+	return resourceIPCollectionMemberRead(ctx, d, m)
+}
+
+// The addresses in the collection including those held in its shards, since the address could have been moved into
+// a shard if the owner of the collection has since taken it over
+func ipCollectionMemberAddresses(ctx context.Context, config *Config, ipCollection *IPCollection) ([]string, error) {
+	var byName map[string]IPCollection
+	for _, member := range ipCollection.Spec.IPCollections {
+		if isIPCollectionShard(ipCollection.Meta.Name, member) {
+			collections, err := listIPCollections(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			byName = ipCollectionsByName(collections)
+			break
+		}
+	}
+	addresses, _, _ := resolveIPCollectionShards(ipCollection, byName)
+	return canonicalAddresses(addresses), nil
+}
+
+func resourceIPCollectionMemberRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	// The collection names can't contain a /, so the address is everything after the first one
+	parts := strings.SplitN(d.Id(), "/", 2)
+	if len(parts) != 2 {
+		return diag.Errorf("invalid ip_collection member ID %q, expected collection/address", d.Id())
+	}
+	name, address := parts[0], parts[1]
+
+	ipCollection, err := getIPCollection(ctx, config, name)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if ipCollection == nil {
+		log.Printf("[WARN] ip_collection %s not found, removing member %s from state", name, address)
+		d.SetId("")
+		return nil
+	}
+
+	addresses, err := ipCollectionMemberAddresses(ctx, config, ipCollection)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	for _, existing := range addresses {
+		if existing == address {
+			d.Set("collection", name)
+			d.Set("address", address)
+			return nil
+		}
+	}
+
+	log.Printf("[WARN] %s is no longer in ip_collection %s, removing from state", address, name)
+	d.SetId("")
+
+	return nil
+}
+
+func resourceIPCollectionMemberDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	name := d.Get("collection").(string)
+	address := canonicalAddresses([]string{d.Get("address").(string)})[0]
+
+	log.Printf("[DEBUG] Removing %s from ip_collection %s", address, name)
+
+	removed, err := removeIPCollectionAddress(ctx, config, name, address)
+	// There is nothing left to remove the address from if the collection has already gone
+	if errors.Is(err, errIPCollectionNotFound) {
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Read also finds the address in the shards of a sharded collection, so it has to be removed from there too
+	if !removed {
+		ipCollection, err := getIPCollection(ctx, config, name)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if ipCollection != nil {
+			for _, member := range ipCollection.Spec.IPCollections {
+				if !isIPCollectionShard(name, member) {
+					continue
+				}
+				log.Printf("[DEBUG] Removing %s from ip_collection %s shard %s", address, name, member)
+				removed, err = removeIPCollectionAddress(ctx, config, member, address)
+				if err != nil && !errors.Is(err, errIPCollectionNotFound) {
+					return diag.FromErr(err)
+				}
+				if removed {
+					break
+				}
+			}
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+// Remove an address from a single collection, returning whether the collection held it
+func removeIPCollectionAddress(ctx context.Context, config *Config, name, address string) (bool, error) {
+	removed := false
+	err := modifyIPCollection(ctx, config, name, func(ipCollection *IPCollection) bool {
+		addresses := []string{}
+		for _, existing := range ipCollection.Spec.Addresses {
+			if canonicalAddresses([]string{existing})[0] != address {
+				addresses = append(addresses, existing)
+			}
+		}
+		removed = len(addresses) != len(ipCollection.Spec.Addresses)
+		if !removed {
+			return false
+		}
+		ipCollection.Spec.Addresses = addresses
+		return true
+	})
+	return removed, err
+}
@@ -0,0 +1,439 @@
+package psm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform resource schema for IPsec policies. Each tunnel is negotiated between a local DSC endpoint
+// and a remote peer, protecting the traffic between the local and remote prefixes. The policy is attached to a VRF
+// with ipsec_policies.
+func resourceIPsecPolicy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceIPsecPolicyCreate,
+		ReadContext:   resourceIPsecPolicyRead,
+		UpdateContext: resourceIPsecPolicyUpdate,
+		DeleteContext: resourceIPsecPolicyDelete,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"tunnel": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"local_endpoint": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPAddress,
+						},
+						"remote_endpoint": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPAddress,
+						},
+						"ike_version": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "ikev2",
+							ValidateFunc: validation.StringInSlice([]string{"ikev1", "ikev2"}, false),
+						},
+						"pre_shared_key": {
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+						},
+						"ike_proposal": ipsecProposalSchema(true),
+						"esp_proposal": ipsecProposalSchema(false),
+						"ike_lifetime": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "24h",
+							ValidateFunc: validateDurationBetween(5*time.Minute, 24*time.Hour),
+						},
+						"esp_lifetime": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "1h",
+							ValidateFunc: validateDurationBetween(5*time.Minute, 8*time.Hour),
+						},
+						"local_prefixes": {
+							Type:     schema.TypeList,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.IsCIDR,
+							},
+						},
+						"remote_prefixes": {
+							Type:     schema.TypeList,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.IsCIDR,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Schema for the proposals offered during IKE and ESP negotiation, in order of preference. The DH group is required
+// for IKE and optional for ESP, where it enables perfect forward secrecy.
+func ipsecProposalSchema(ike bool) *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Required: true,
+		MinItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"encryption": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"aes-128-cbc", "aes-256-cbc", "aes-128-gcm", "aes-256-gcm"}, false),
+				},
+				"integrity": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "sha-256",
+					ValidateFunc: validation.StringInSlice([]string{"sha-256", "sha-384", "sha-512"}, false),
+				},
+				"dh_group": {
+					Type:         schema.TypeString,
+					Required:     ike,
+					Optional:     !ike,
+					ValidateFunc: validation.StringInSlice([]string{"group14", "group15", "group16", "group19", "group20", "group21"}, false),
+				},
+			},
+		},
+	}
+}
+
+type IPsecPolicy struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	Meta       struct {
+		Name            string      `json:"name"`
+		Tenant          string      `json:"tenant"`
+		Namespace       interface{} `json:"namespace"`
+		GenerationID    interface{} `json:"generation-id"`
+		ResourceVersion interface{} `json:"resource-version"`
+		UUID            interface{} `json:"uuid"`
+		Labels          interface{} `json:"labels"`
+		SelfLink        interface{} `json:"self-link"`
+		DisplayName     interface{} `json:"display-name"`
+	} `json:"meta"`
+	Spec struct {
+		Tunnels []IPsecTunnel `json:"tunnels"`
+	} `json:"spec"`
+}
+
+type IPsecTunnel struct {
+	Name           string   `json:"name"`
+	LocalEndpoint  string   `json:"local-endpoint"`
+	RemoteEndpoint string   `json:"remote-endpoint"`
+	IKE            IPsecSA  `json:"ike"`
+	ESP            IPsecSA  `json:"esp"`
+	LocalPrefixes  []string `json:"local-prefixes"`
+	RemotePrefixes []string `json:"remote-prefixes"`
+}
+
+type IPsecSA struct {
+	Version      string          `json:"version,omitempty"`
+	PreSharedKey string          `json:"pre-shared-key,omitempty"`
+	Proposals    []IPsecProposal `json:"proposals"`
+	Lifetime     string          `json:"sa-lifetime"`
+}
+
+type IPsecProposal struct {
+	Encryption string `json:"encryption-algorithm"`
+	Integrity  string `json:"integrity-algorithm"`
+	DHGroup    string `json:"dh-group,omitempty"`
+}
+
+func expandIPsecProposals(v []interface{}) []IPsecProposal {
+	proposals := []IPsecProposal{}
+	for _, p := range v {
+		proposal := p.(map[string]interface{})
+		proposals = append(proposals, IPsecProposal{
+			Encryption: proposal["encryption"].(string),
+			Integrity:  proposal["integrity"].(string),
+			DHGroup:    proposal["dh_group"].(string),
+		})
+	}
+	return proposals
+}
+
+func flattenIPsecProposals(proposals []IPsecProposal) []interface{} {
+	result := make([]interface{}, len(proposals))
+	for i, proposal := range proposals {
+		result[i] = map[string]interface{}{
+			"encryption": proposal.Encryption,
+			"integrity":  proposal.Integrity,
+			"dh_group":   proposal.DHGroup,
+		}
+	}
+	return result
+}
+
+// Build the list of tunnels from the tunnel blocks in the resource definition
+func expandIPsecTunnels(d *schema.ResourceData) []IPsecTunnel {
+	tunnels := []IPsecTunnel{}
+	for _, v := range d.Get("tunnel").([]interface{}) {
+		tunnel := v.(map[string]interface{})
+		tunnels = append(tunnels, IPsecTunnel{
+			Name:           tunnel["name"].(string),
+			LocalEndpoint:  tunnel["local_endpoint"].(string),
+			RemoteEndpoint: tunnel["remote_endpoint"].(string),
+			IKE: IPsecSA{
+				Version:      tunnel["ike_version"].(string),
+				PreSharedKey: tunnel["pre_shared_key"].(string),
+				Proposals:    expandIPsecProposals(tunnel["ike_proposal"].([]interface{})),
+				Lifetime:     tunnel["ike_lifetime"].(string),
+			},
+			ESP: IPsecSA{
+				Proposals: expandIPsecProposals(tunnel["esp_proposal"].([]interface{})),
+				Lifetime:  tunnel["esp_lifetime"].(string),
+			},
+			LocalPrefixes:  convertToStringSlice(tunnel["local_prefixes"].([]interface{})),
+			RemotePrefixes: convertToStringSlice(tunnel["remote_prefixes"].([]interface{})),
+		})
+	}
+	return tunnels
+}
+
+// PSM doesn't return the pre-shared key, so the key for each tunnel is carried over from the existing state
+func flattenIPsecTunnels(d *schema.ResourceData, tunnels []IPsecTunnel) []interface{} {
+	presharedKeys := map[string]string{}
+	for _, v := range d.Get("tunnel").([]interface{}) {
+		tunnel := v.(map[string]interface{})
+		presharedKeys[tunnel["name"].(string)] = tunnel["pre_shared_key"].(string)
+	}
+
+	result := make([]interface{}, len(tunnels))
+	for i, tunnel := range tunnels {
+		result[i] = map[string]interface{}{
+			"name":            tunnel.Name,
+			"local_endpoint":  tunnel.LocalEndpoint,
+			"remote_endpoint": tunnel.RemoteEndpoint,
+			"ike_version":     tunnel.IKE.Version,
+			"pre_shared_key":  presharedKeys[tunnel.Name],
+			"ike_proposal":    flattenIPsecProposals(tunnel.IKE.Proposals),
+			"esp_proposal":    flattenIPsecProposals(tunnel.ESP.Proposals),
+			"ike_lifetime":    tunnel.IKE.Lifetime,
+			"esp_lifetime":    tunnel.ESP.Lifetime,
+			"local_prefixes":  tunnel.LocalPrefixes,
+			"remote_prefixes": tunnel.RemotePrefixes,
+		}
+	}
+	return result
+}
+
+func resourceIPsecPolicyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	policy := &IPsecPolicy{}
+	policy.Meta.Name = d.Get("name").(string)
+	policy.Meta.Tenant = "default"
+	policy.Spec.Tunnels = expandIPsecTunnels(d)
+
+	jsonBytes, err := json.Marshal(policy)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// The request body contains the pre-shared keys so it is never logged
+	log.Printf("[DEBUG] Creating IPsec policy with name: %s", policy.Meta.Name)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.Server+"/configs/network/v1/tenant/default/ipsecpolicies", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] Error when creating IPsec policy: %s", err)
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		errMsg := fmt.Sprintf("failed to create IPsec policy: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "IPsec policy creation failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	responseBody := &IPsecPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(responseBody); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(responseBody.Meta.UUID.(string))
+
+	return append(diag.Diagnostics{}, resourceIPsecPolicyRead(ctx, d, m)...)
+}
+
+func resourceIPsecPolicyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipsecpolicies/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		d.SetId("")
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to read IPsec policy: HTTP %s", resp.Status)
+	}
+
+	policy := &IPsecPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policy); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.Set("name", policy.Meta.Name)
+	if err := d.Set("tunnel", flattenIPsecTunnels(d, policy.Spec.Tunnels)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceIPsecPolicyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipsecpolicies/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to get current IPsec policy state: HTTP %s", resp.Status)
+	}
+
+	policyCurrent := &IPsecPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policyCurrent); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// The whole tunnel list is sent as PSM requires the pre-shared key for every tunnel on update
+	policyCurrent.Spec.Tunnels = expandIPsecTunnels(d)
+
+	jsonBytes, err := json.Marshal(policyCurrent)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	respUpdate, err := client.Do(reqUpdate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer respUpdate.Body.Close()
+
+	if respUpdate.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(respUpdate.Body)
+		errMsg := fmt.Sprintf("failed to update IPsec policy: HTTP %d %s: %s", respUpdate.StatusCode, respUpdate.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "IPsec policy update failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	return resourceIPsecPolicyRead(ctx, d, m)
+}
+
+func resourceIPsecPolicyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/ipsecpolicies/" + d.Get("name").(string)
+
+	log.Printf("[DEBUG] Deleting IPsec policy with name: %s", d.Get("name").(string))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return diag.Errorf("failed to delete IPsec policy: HTTP %s", resp.Status)
+	}
+
+	d.SetId("")
+
+	return nil
+}
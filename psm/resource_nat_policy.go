@@ -0,0 +1,370 @@
+package psm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform resource schema for NAT policies. Rules match traffic using IP collections and translate
+// either the source or destination address. Static rules translate to an IP collection of the same size while
+// dynamic rules translate the source address to an address pool.
+func resourceNATPolicy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceNATPolicyCreate,
+		ReadContext:   resourceNATPolicyRead,
+		UpdateContext: resourceNATPolicyUpdate,
+		DeleteContext: resourceNATPolicyDelete,
+		CustomizeDiff: resourceNATPolicyCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"rule": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"rule_name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"type": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "static",
+							ValidateFunc: validation.StringInSlice([]string{"static", "dynamic"}, false),
+						},
+						"nat_type": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "source",
+							ValidateFunc: validation.StringInSlice([]string{"source", "destination"}, false),
+						},
+						"from_ip_collections": {
+							Type:     schema.TypeList,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Optional: true,
+						},
+						"to_ip_collections": {
+							Type:     schema.TypeList,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Optional: true,
+						},
+						"translated_ip_collection": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"translated_address_pool": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type NATPolicy struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	Meta       struct {
+		Name            string      `json:"name"`
+		Tenant          string      `json:"tenant"`
+		Namespace       interface{} `json:"namespace"`
+		GenerationID    interface{} `json:"generation-id"`
+		ResourceVersion interface{} `json:"resource-version"`
+		UUID            interface{} `json:"uuid"`
+		Labels          interface{} `json:"labels"`
+		SelfLink        interface{} `json:"self-link"`
+		DisplayName     interface{} `json:"display-name"`
+	} `json:"meta"`
+	Spec struct {
+		Rules []NATRule `json:"rules"`
+	} `json:"spec"`
+}
+
+type NATRule struct {
+	Name                   string   `json:"name"`
+	Type                   string   `json:"type"`
+	NatType                string   `json:"nat-type"`
+	FromIPCollections      []string `json:"from-ipcollections"`
+	ToIPCollections        []string `json:"to-ipcollections"`
+	TranslatedIPCollection string   `json:"translated-ipcollection,omitempty"`
+	TranslatedAddressPool  string   `json:"translated-address-pool,omitempty"`
+}
+
+// Check each rule has the translation target that matches its type, as PSM only rejects these once the policy is
+// being propagated to the DSEs.
+func resourceNATPolicyCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	for i, v := range d.Get("rule").([]interface{}) {
+		if v == nil {
+			continue
+		}
+		rule := v.(map[string]interface{})
+		name := rule["rule_name"].(string)
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i)
+		}
+
+		collection := rule["translated_ip_collection"].(string)
+		pool := rule["translated_address_pool"].(string)
+
+		// Targets that reference other resources may not be known until apply
+		collectionKnown := d.NewValueKnown(fmt.Sprintf("rule.%d.translated_ip_collection", i))
+		poolKnown := d.NewValueKnown(fmt.Sprintf("rule.%d.translated_address_pool", i))
+
+		switch rule["type"].(string) {
+		case "static":
+			if pool != "" {
+				return fmt.Errorf("%s: static NAT rules translate to a translated_ip_collection, not an address pool", name)
+			}
+			if collection == "" && collectionKnown {
+				return fmt.Errorf("%s: static NAT rules require a translated_ip_collection", name)
+			}
+		case "dynamic":
+			if rule["nat_type"].(string) != "source" {
+				return fmt.Errorf("%s: dynamic NAT is only supported for source NAT", name)
+			}
+			if collection != "" {
+				return fmt.Errorf("%s: dynamic NAT rules translate to a translated_address_pool, not an IP collection", name)
+			}
+			if pool == "" && poolKnown {
+				return fmt.Errorf("%s: dynamic NAT rules require a translated_address_pool", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Build the list of NAT rules from the rule blocks in the resource definition, keeping the order they are defined
+func expandNATRules(d *schema.ResourceData) []NATRule {
+	rules := []NATRule{}
+	for _, v := range d.Get("rule").([]interface{}) {
+		ruleMap := v.(map[string]interface{})
+		rules = append(rules, NATRule{
+			Name:                   ruleMap["rule_name"].(string),
+			Type:                   ruleMap["type"].(string),
+			NatType:                ruleMap["nat_type"].(string),
+			FromIPCollections:      convertToStringSlice(ruleMap["from_ip_collections"].([]interface{})),
+			ToIPCollections:        convertToStringSlice(ruleMap["to_ip_collections"].([]interface{})),
+			TranslatedIPCollection: ruleMap["translated_ip_collection"].(string),
+			TranslatedAddressPool:  ruleMap["translated_address_pool"].(string),
+		})
+	}
+	return rules
+}
+
+func flattenNATRules(rules []NATRule) []interface{} {
+	result := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		result[i] = map[string]interface{}{
+			"rule_name":                rule.Name,
+			"type":                     rule.Type,
+			"nat_type":                 rule.NatType,
+			"from_ip_collections":      rule.FromIPCollections,
+			"to_ip_collections":        rule.ToIPCollections,
+			"translated_ip_collection": rule.TranslatedIPCollection,
+			"translated_address_pool":  rule.TranslatedAddressPool,
+		}
+	}
+	return result
+}
+
+func resourceNATPolicyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	policy := &NATPolicy{}
+	policy.Meta.Name = d.Get("name").(string)
+	policy.Meta.Tenant = "default"
+	policy.Spec.Rules = expandNATRules(d)
+
+	jsonBytes, err := json.Marshal(policy)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	log.Printf("[DEBUG] Creating NAT policy with name: %s", policy.Meta.Name)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.Server+"/configs/network/v1/tenant/default/natpolicies", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] Error when creating NAT policy: %s", err)
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		errMsg := fmt.Sprintf("failed to create NAT policy: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "NAT policy creation failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	responseBody := &NATPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(responseBody); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(responseBody.Meta.UUID.(string))
+
+	return append(diag.Diagnostics{}, resourceNATPolicyRead(ctx, d, m)...)
+}
+
+func resourceNATPolicyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/natpolicies/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		d.SetId("")
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to read NAT policy: HTTP %s", resp.Status)
+	}
+
+	policy := &NATPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policy); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.Set("name", policy.Meta.Name)
+	if err := d.Set("rule", flattenNATRules(policy.Spec.Rules)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceNATPolicyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/natpolicies/" + d.Get("name").(string)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return diag.Errorf("failed to get current NAT policy state: HTTP %s", resp.Status)
+	}
+
+	policyCurrent := &NATPolicy{}
+	if err := json.NewDecoder(resp.Body).Decode(policyCurrent); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if d.HasChange("rule") {
+		policyCurrent.Spec.Rules = expandNATRules(d)
+	}
+
+	jsonBytes, err := json.Marshal(policyCurrent)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	reqUpdate.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	respUpdate, err := client.Do(reqUpdate)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer respUpdate.Body.Close()
+
+	if respUpdate.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(respUpdate.Body)
+		errMsg := fmt.Sprintf("failed to update NAT policy: HTTP %d %s: %s", respUpdate.StatusCode, respUpdate.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "NAT policy update failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	return resourceNATPolicyRead(ctx, d, m)
+}
+
+func resourceNATPolicyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/natpolicies/" + d.Get("name").(string)
+
+	log.Printf("[DEBUG] Deleting NAT policy with name: %s", d.Get("name").(string))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return diag.Errorf("failed to delete NAT policy: HTTP %s", resp.Status)
+	}
+
+	d.SetId("")
+
+	return nil
+}
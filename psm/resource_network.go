@@ -8,10 +8,13 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 func resourceNetwork() *schema.Resource {
@@ -20,6 +23,7 @@ func resourceNetwork() *schema.Resource {
 		ReadContext:   resourceNetworkRead,
 		UpdateContext: resourceNetworkUpdate,
 		DeleteContext: resourceNetworkDelete,
+		CustomizeDiff: resourceNetworkCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:     schema.TypeString,
@@ -38,13 +42,46 @@ func resourceNetwork() *schema.Resource {
 				Default:  0,
 				ForceNew: true,
 			},
-			"ingress_security_policy": {
+			"ingress_security_policy":   legacySecurityPolicySchema("ingress_security_policies"),
+			"egress_security_policy":    legacySecurityPolicySchema("egress_security_policies"),
+			"ingress_security_policies": securityPoliciesSchema("ingress_security_policy"),
+			"egress_security_policies":  securityPoliciesSchema("egress_security_policy"),
+			"ipam_policy": {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
-			"egress_security_policy": {
-				Type:     schema.TypeString,
+			"route_import_export": routeImportExportSchema(false),
+			"ipv4_subnet": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsCIDR),
+			},
+			"ipv4_gateway": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsIPv4Address),
+			},
+			"ipv6_subnet": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsCIDR),
+			},
+			"ipv6_gateway": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsIPv6Address),
+			},
+			// Keeps an IP collection with the same name as the network holding its subnets, so rules can use it
+			"create_ip_collection": {
+				Type:     schema.TypeBool,
 				Optional: true,
+				Default:  false,
+			},
+			// The addresses in the generated IP collection, used to notice when it has been changed or removed
+			"ip_collection_addresses": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 		},
 	}
@@ -73,7 +110,7 @@ type Network struct {
 		VlanID                int           `json:"vlan-id"`
 		VxlanVni              interface{}   `json:"vxlan-vni" default:"null`
 		VirtualRouter         string        `json:"virtual-router"`
-		IpamPolicy            interface{}   `json:"ipam-policy" default:"null`
+		IpamPolicy            interface{}   `json:"ipam-policy"`
 		Orchestrators         []interface{} `json:"orchestrators"`
 		IngressSecurityPolicy []interface{} `json:"ingress-security-policy" default:"null`
 		EgressSecurityPolicy  []interface{} `json:"egress-security-policy" default:"null`
@@ -81,11 +118,139 @@ type Network struct {
 			MaximumCpsPerDistributedServicesEntity      int `json:"maximum-cps-per-distributed-services-entity" default:"-1"`
 			MaximumSessionsPerDistributedServicesEntity int `json:"maximum-sessions-per-distributed-services-entity default:"-1"`
 		} `json:"firewall-profile"`
-		SelectVlanOrIpv4  int         `json:"selectVlanOrIpv4" default:"1"`
-		SelectCPS         int         `json:"selectCPS" default:"-1"`
-		SelectSessions    int         `json:"selectSessions" default:"-1"`
-		RouteImportExport interface{} `json:"route-import-export" default:"null"`
+		SelectVlanOrIpv4  int     `json:"selectVlanOrIpv4" default:"1"`
+		SelectCPS         int     `json:"selectCPS" default:"-1"`
+		SelectSessions    int     `json:"selectSessions" default:"-1"`
+		RouteImportExport *RDSpec `json:"route-import-export"`
+	}
+}
+
+type NetworkList struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	ListMeta   interface{} `json:"list-meta"`
+	Items      []Network   `json:"items"`
+}
+
+// Retrieve all of the networks in the tenant with a single call, optionally restricted by a PSM label selector
+// such as "department=Production".
+func listNetworks(ctx context.Context, config *Config, labelSelector string) ([]Network, error) {
+	client := config.Client()
+
+	listURL := config.Server + "/configs/network/v1/tenant/default/networks"
+	if labelSelector != "" {
+		listURL += "?label-selector=" + url.QueryEscape(labelSelector)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list networks: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	networks := &NetworkList{}
+	if err := json.NewDecoder(resp.Body).Decode(networks); err != nil {
+		return nil, err
+	}
+
+	return networks.Items, nil
+}
+
+// Set the subnets and gateways of the network, clearing any that are no longer defined
+func expandNetworkSubnets(d *schema.ResourceData, network *Network) {
+	optional := func(key string) interface{} {
+		if v := d.Get(key).(string); v != "" {
+			return v
+		}
+		return nil
+	}
+
+	network.Spec.Ipv4Subnet = optional("ipv4_subnet")
+	network.Spec.Ipv4Gateway = optional("ipv4_gateway")
+	network.Spec.Ipv6Subnet = optional("ipv6_subnet")
+	network.Spec.Ipv6Gateway = optional("ipv6_gateway")
+}
+
+// The addresses of the IP collection generated for a network, one for each of its subnets. Takes either the
+// ResourceData or the ResourceDiff of the network.
+func networkIPCollectionAddresses(d interface{ Get(string) interface{} }) []string {
+	addresses := []string{}
+	for _, key := range []string{"ipv4_subnet", "ipv6_subnet"} {
+		if v := d.Get(key).(string); v != "" {
+			addresses = append(addresses, v)
+		}
+	}
+	return canonicalAddresses(addresses)
+}
+
+// Create or update the IP collection generated for a network so it holds the network's subnets. An existing
+// collection is only updated when the network already owns it, anything else with the same name is left alone.
+func syncNetworkIPCollection(ctx context.Context, config *Config, d *schema.ResourceData, owned bool) error {
+	name := d.Get("name").(string)
+	addresses := networkIPCollectionAddresses(d)
+
+	ipCollection, err := getIPCollection(ctx, config, name)
+	if err != nil {
+		return err
+	}
+	if ipCollection != nil && !owned {
+		return errNetworkIPCollectionExists(name)
+	}
+
+	if ipCollection == nil {
+		log.Printf("[DEBUG] Creating IP collection for network %s", name)
+		ipCollection = &IPCollection{}
+		ipCollection.Meta.Name = name
+		ipCollection.Spec.Addresses = addresses
+		ipCollection.Spec.IPCollections = []string{}
+		return createIPCollection(ctx, config, ipCollection)
+	}
+
+	if strings.Join(canonicalAddresses(ipCollection.Spec.Addresses), ",") == strings.Join(addresses, ",") {
+		return nil
+	}
+
+	log.Printf("[DEBUG] Updating IP collection for network %s", name)
+	ipCollection.Spec.Addresses = addresses
+	return putIPCollection(ctx, config, ipCollection)
+}
+
+func errNetworkIPCollectionExists(name string) error {
+	return fmt.Errorf("ip_collection %q already exists, remove it or set create_ip_collection = false to use it as it is", name)
+}
+
+// Plan an update of the generated IP collection whenever its addresses no longer match the subnets, including when
+// it has been removed outside Terraform
+func resourceNetworkCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("create_ip_collection") || !d.NewValueKnown("ipv4_subnet") || !d.NewValueKnown("ipv6_subnet") {
+		return d.SetNewComputed("ip_collection_addresses")
+	}
+
+	addresses := []string{}
+	if d.Get("create_ip_collection").(bool) {
+		addresses = networkIPCollectionAddresses(d)
+	}
+
+	current := []string{}
+	for _, address := range d.Get("ip_collection_addresses").([]interface{}) {
+		current = append(current, address.(string))
+	}
+	if strings.Join(current, ",") == strings.Join(addresses, ",") {
+		return nil
 	}
+	return d.SetNew("ip_collection_addresses", addresses)
 }
 
 func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -100,12 +265,30 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, m interf
 	network.Meta.Namespace = "default"
 	network.Spec.VirtualRouter = d.Get("tenant").(string)
 
-	// Check if the ingress_security_policy and egress_security_policy values are provided and set them
-	if v, ok := d.GetOk("ingress_security_policy"); ok {
-		network.Spec.IngressSecurityPolicy = []interface{}{v.(string)}
+	// Attach the ingress and egress security policies in the order they are defined
+	network.Spec.IngressSecurityPolicy = expandSecurityPolicies(d, "ingress_security_policies", "ingress_security_policy")
+	network.Spec.EgressSecurityPolicy = expandSecurityPolicies(d, "egress_security_policies", "egress_security_policy")
+	if v, ok := d.GetOk("ipam_policy"); ok {
+		network.Spec.IpamPolicy = v.(string)
+	}
+	expandNetworkSubnets(d, network)
+
+	routeImportExport, err := expandRouteImportExport(d.Get("route_import_export").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
 	}
-	if v, ok := d.GetOk("egress_security_policy"); ok {
-		network.Spec.EgressSecurityPolicy = []interface{}{v.(string)}
+	network.Spec.RouteImportExport = routeImportExport
+
+	// Check the name of the generated IP collection is free before the network is created, so a failure doesn't
+	// leave a network in the state that would delete someone else's collection when destroyed
+	if d.Get("create_ip_collection").(bool) {
+		ipCollection, err := getIPCollection(ctx, config, network.Meta.Name)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if ipCollection != nil {
+			return diag.FromErr(errNetworkIPCollectionExists(network.Meta.Name))
+		}
 	}
 
 	// Convert the Network struct to JSON.
@@ -151,6 +334,12 @@ func resourceNetworkCreate(ctx context.Context, d *schema.ResourceData, m interf
 	// Set the Terraform resource ID to the UUID returned by the API.
 	d.SetId(responseBody.Meta.UUID)
 
+	if d.Get("create_ip_collection").(bool) {
+		if err := syncNetworkIPCollection(ctx, config, d, false); err != nil {
+			return append(diag.FromErr(err), resourceNetworkRead(ctx, d, m)...)
+		}
+	}
+
 	return append(diag.Diagnostics{}, resourceNetworkRead(ctx, d, m)...)
 
 	//return diag.Diagnostics{}
@@ -190,6 +379,61 @@ func resourceNetworkRead(ctx context.Context, d *schema.ResourceData, m interfac
 
 	d.Set("name", network.Meta.Name)
 	d.Set("vlan_id", network.Spec.VlanID)
+	if ipamPolicy, ok := network.Spec.IpamPolicy.(string); ok {
+		d.Set("ipam_policy", ipamPolicy)
+	} else {
+		d.Set("ipam_policy", "")
+	}
+	if err := d.Set("route_import_export", flattenRouteImportExport(network.Spec.RouteImportExport)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := flattenSecurityPolicies(d, "ingress_security_policies", "ingress_security_policy", network.Spec.IngressSecurityPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := flattenSecurityPolicies(d, "egress_security_policies", "egress_security_policy", network.Spec.EgressSecurityPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	d.Set("ipv4_subnet", interfaceToString(network.Spec.Ipv4Subnet))
+	d.Set("ipv4_gateway", interfaceToString(network.Spec.Ipv4Gateway))
+	d.Set("ipv6_subnet", interfaceToString(network.Spec.Ipv6Subnet))
+	d.Set("ipv6_gateway", interfaceToString(network.Spec.Ipv6Gateway))
+
+	// Record what the generated IP collection holds, so the next plan puts it back if it has changed or gone
+	ipCollectionAddresses := []string{}
+	if d.Get("create_ip_collection").(bool) {
+		ipCollection, err := getIPCollection(ctx, config, network.Meta.Name)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if ipCollection != nil {
+			ipCollectionAddresses = canonicalAddresses(ipCollection.Spec.Addresses)
+		} else {
+			log.Printf("[WARN] IP collection for network %s is missing", network.Meta.Name)
+		}
+	}
+	d.Set("ip_collection_addresses", ipCollectionAddresses)
+
+	return nil
+}
+
+// Delete the IP collection generated for a network, as long as no rules still reference it
+func deleteNetworkIPCollection(ctx context.Context, config *Config, name string) diag.Diagnostics {
+	policies, err := listSecurityPolicies(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	collections, err := listIPCollections(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if blockers := ipCollectionDependents(policies, collections, name); len(blockers) > 0 {
+		return dependencyDiagnostics("IP collection", name, blockers, "")
+	}
+
+	log.Printf("[DEBUG] Deleting IP collection for network %s", name)
+	if err := deleteIPCollection(ctx, config, name); err != nil {
+		return diag.FromErr(err)
+	}
 
 	return nil
 }
@@ -231,22 +475,32 @@ func resourceNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interf
 		return diag.FromErr(err)
 	}
 
-	if d.HasChange("ingress_security_policy") {
-		if val, ok := d.GetOk("ingress_security_policy"); ok {
-			newIngressPolicy := val.(string)
-			networkCurrent.Spec.IngressSecurityPolicy = []interface{}{newIngressPolicy}
+	if d.HasChanges("ingress_security_policy", "ingress_security_policies") {
+		networkCurrent.Spec.IngressSecurityPolicy = expandSecurityPolicies(d, "ingress_security_policies", "ingress_security_policy")
+	}
+
+	if d.HasChanges("egress_security_policy", "egress_security_policies") {
+		networkCurrent.Spec.EgressSecurityPolicy = expandSecurityPolicies(d, "egress_security_policies", "egress_security_policy")
+	}
+
+	if d.HasChange("ipam_policy") {
+		if val, ok := d.GetOk("ipam_policy"); ok {
+			networkCurrent.Spec.IpamPolicy = val.(string)
 		} else {
-			networkCurrent.Spec.IngressSecurityPolicy = nil
+			networkCurrent.Spec.IpamPolicy = nil
 		}
 	}
 
-	if d.HasChange("egress_security_policy") {
-		if val, ok := d.GetOk("egress_security_policy"); ok {
-			newEgressPolicy := val.(string)
-			networkCurrent.Spec.EgressSecurityPolicy = []interface{}{newEgressPolicy}
-		} else {
-			networkCurrent.Spec.EgressSecurityPolicy = nil
+	if d.HasChange("route_import_export") {
+		routeImportExport, err := expandRouteImportExport(d.Get("route_import_export").([]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
 		}
+		networkCurrent.Spec.RouteImportExport = routeImportExport
+	}
+
+	if d.HasChanges("ipv4_subnet", "ipv4_gateway", "ipv6_subnet", "ipv6_gateway") {
+		expandNetworkSubnets(d, networkCurrent)
 	}
 
 	jsonBytes, err := json.Marshal(networkCurrent)
@@ -286,6 +540,21 @@ func resourceNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interf
 		log.Printf("[DEBUG] Network updated successfully")
 	}
 
+	if d.HasChanges("create_ip_collection", "ipv4_subnet", "ipv6_subnet", "ip_collection_addresses") {
+		if d.Get("create_ip_collection").(bool) {
+			// Keep create_ip_collection as it was if the collection can't be taken on, so a destroy won't remove it
+			o, _ := d.GetChange("create_ip_collection")
+			if err := syncNetworkIPCollection(ctx, config, d, o.(bool)); err != nil {
+				d.Partial(true)
+				return diag.FromErr(err)
+			}
+		} else if o, _ := d.GetChange("create_ip_collection"); o.(bool) {
+			if diags := deleteNetworkIPCollection(ctx, config, d.Get("name").(string)); diags.HasError() {
+				return diags
+			}
+		}
+	}
+
 	return resourceNetworkRead(ctx, d, m)
 }
 
@@ -293,6 +562,22 @@ func resourceNetworkDelete(ctx context.Context, d *schema.ResourceData, m interf
 	config := m.(*Config)
 	client := config.Client()
 
+	// Workloads attached to the network would be left without a network, so refuse to delete it while they exist
+	workloads, err := listWorkloads(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if blockers := networkDependents(workloads, d.Get("name").(string), d.Get("vlan_id").(int)); len(blockers) > 0 {
+		return dependencyDiagnostics("network", d.Get("name").(string), blockers, "")
+	}
+
+	// The generated IP collection goes first, so rules still using it stop the network from being deleted
+	if d.Get("create_ip_collection").(bool) {
+		if diags := deleteNetworkIPCollection(ctx, config, d.Get("name").(string)); diags.HasError() {
+			return diags
+		}
+	}
+
 	// Construct the URL for the network based on its name
 
 	url := config.Server + "/configs/network/v1/tenant/default/networks/" + d.Get("name").(string)
@@ -0,0 +1,554 @@
+package psm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// The number of networks created, updated or deleted against the PSM server at the same time
+const networkSetParallelism = 8
+
+// Define the Terraform resource schema for a set of networks. This manages many networks as a single Terraform
+// object, which is far quicker to refresh than one psm_network per VLAN as all members are read with one list call.
+func resourceNetworkSet() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceNetworkSetCreate,
+		ReadContext:   resourceNetworkSetRead,
+		UpdateContext: resourceNetworkSetUpdate,
+		DeleteContext: resourceNetworkSetDelete,
+		CustomizeDiff: resourceNetworkSetCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"network": {
+				Type:     schema.TypeSet,
+				Required: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"vlan_id": {
+							Type:         schema.TypeInt,
+							Required:     true,
+							ValidateFunc: validation.IntBetween(1, 4094),
+						},
+						"vrf": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "default",
+						},
+						"ipv4_subnet": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsCIDR),
+						},
+						"ipv4_gateway": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.Any(validation.StringIsEmpty, validation.IsIPv4Address),
+						},
+						"ipam_policy": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"ingress_security_policies": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"egress_security_policies": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Network names must be unique, as must the VLAN used within each VRF
+func resourceNetworkSetCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	names := map[string]bool{}
+	vlans := map[string]string{}
+	for _, v := range d.Get("network").(*schema.Set).List() {
+		member := v.(map[string]interface{})
+		name := member["name"].(string)
+		if name == "" {
+			continue
+		}
+		if names[name] {
+			return fmt.Errorf("network %q is defined more than once", name)
+		}
+		names[name] = true
+
+		// Values that aren't known until apply can't be checked
+		if member["vlan_id"].(int) == 0 {
+			continue
+		}
+		vlanKey := fmt.Sprintf("%s/%d", member["vrf"].(string), member["vlan_id"].(int))
+		if other, ok := vlans[vlanKey]; ok {
+			return fmt.Errorf("networks %q and %q both use VLAN %d in VRF %q", other, name, member["vlan_id"].(int), member["vrf"].(string))
+		}
+		vlans[vlanKey] = name
+	}
+	return nil
+}
+
+// Index the members of the network block by name
+func networkSetMembers(v interface{}) map[string]map[string]interface{} {
+	members := map[string]map[string]interface{}{}
+	for _, member := range v.(*schema.Set).List() {
+		memberMap := member.(map[string]interface{})
+		members[memberMap["name"].(string)] = memberMap
+	}
+	return members
+}
+
+// Apply the member settings onto a network, leaving anything not managed by the set untouched
+func expandNetworkSetMember(member map[string]interface{}, network *Network) {
+	network.Meta.Name = member["name"].(string)
+	network.Meta.Tenant = "default"
+	network.Meta.Namespace = "default"
+	network.Spec.Type = "bridged"
+	network.Spec.VlanID = member["vlan_id"].(int)
+	network.Spec.VirtualRouter = member["vrf"].(string)
+
+	network.Spec.Ipv4Subnet = nil
+	if v := member["ipv4_subnet"].(string); v != "" {
+		network.Spec.Ipv4Subnet = v
+	}
+	network.Spec.Ipv4Gateway = nil
+	if v := member["ipv4_gateway"].(string); v != "" {
+		network.Spec.Ipv4Gateway = v
+	}
+	network.Spec.IpamPolicy = nil
+	if v := member["ipam_policy"].(string); v != "" {
+		network.Spec.IpamPolicy = v
+	}
+
+	network.Spec.IngressSecurityPolicy = nil
+	if v := member["ingress_security_policies"].([]interface{}); len(v) > 0 {
+		network.Spec.IngressSecurityPolicy = v
+	}
+	network.Spec.EgressSecurityPolicy = nil
+	if v := member["egress_security_policies"].([]interface{}); len(v) > 0 {
+		network.Spec.EgressSecurityPolicy = v
+	}
+}
+
+func flattenNetworkSetMember(network *Network) map[string]interface{} {
+	ingress := network.Spec.IngressSecurityPolicy
+	if ingress == nil {
+		ingress = []interface{}{}
+	}
+	egress := network.Spec.EgressSecurityPolicy
+	if egress == nil {
+		egress = []interface{}{}
+	}
+
+	return map[string]interface{}{
+		"name":                      network.Meta.Name,
+		"vlan_id":                   network.Spec.VlanID,
+		"vrf":                       network.Spec.VirtualRouter,
+		"ipv4_subnet":               interfaceToString(network.Spec.Ipv4Subnet),
+		"ipv4_gateway":              interfaceToString(network.Spec.Ipv4Gateway),
+		"ipam_policy":               interfaceToString(network.Spec.IpamPolicy),
+		"ingress_security_policies": ingress,
+		"egress_security_policies":  egress,
+	}
+}
+
+// Send a single network to the PSM server, using POST to create it or PUT to update an existing network
+func sendNetwork(ctx context.Context, config *Config, method string, network *Network) error {
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/networks"
+	if method == "PUT" {
+		url += "/" + network.Meta.Name
+	}
+
+	jsonBytes, err := json.Marshal(network)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	return nil
+}
+
+func deleteNetwork(ctx context.Context, config *Config, name string) error {
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/networks/" + name
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	return nil
+}
+
+// A change to a single member of the set
+type networkSetOperation struct {
+	name   string
+	action string
+	run    func() error
+}
+
+// Run the member operations in parallel, returning one diagnostic for each member that failed so the user can
+// see exactly which networks were not applied, along with the names of those networks.
+func applyNetworkSetOperations(operations []networkSetOperation) (diag.Diagnostics, map[string]bool) {
+	var diags diag.Diagnostics
+	failed := map[string]bool{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, networkSetParallelism)
+
+	for _, op := range operations {
+		op := op
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			log.Printf("[DEBUG] Network set: %s network %s", op.action, op.name)
+			if err := op.run(); err != nil {
+				mutex.Lock()
+				failed[op.name] = true
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Error,
+					Summary:  fmt.Sprintf("Failed to %s network %s", op.action, op.name),
+					Detail:   err.Error(),
+				})
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(diags, func(i, j int) bool {
+		return diags[i].Summary < diags[j].Summary
+	})
+
+	return diags, failed
+}
+
+func resourceNetworkSetCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	members := networkSetMembers(d.Get("network"))
+
+	// Networks that already exist belong to something else, so refuse to create the set rather than adopt them
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	var diags diag.Diagnostics
+	for _, network := range networks {
+		if _, ok := members[network.Meta.Name]; ok {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Network %s already exists", network.Meta.Name),
+				Detail:   "Import the network or remove it from PSM before adding it to the set.",
+			})
+		}
+	}
+	if diags.HasError() {
+		return diags
+	}
+
+	operations := []networkSetOperation{}
+	for name, member := range members {
+		network := &Network{}
+		expandNetworkSetMember(member, network)
+		operations = append(operations, networkSetOperation{
+			name:   name,
+			action: "create",
+			run: func() error {
+				return sendNetwork(ctx, config, "POST", network)
+			},
+		})
+	}
+
+	d.SetId(d.Get("name").(string))
+
+	// Only the networks the set created are recorded, so a failed create is never mistaken for a member
+	diags, failed := applyNetworkSetOperations(operations)
+	names := map[string]bool{}
+	for name := range members {
+		if !failed[name] {
+			names[name] = true
+		}
+	}
+
+	return append(diags, readNetworkSet(ctx, d, config, names)...)
+}
+
+func resourceNetworkSetRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	names := map[string]bool{}
+	for name := range networkSetMembers(d.Get("network")) {
+		names[name] = true
+	}
+	return readNetworkSet(ctx, d, m.(*Config), names)
+}
+
+// Refresh the named members of the set from a single list of the networks in PSM. Any that have been removed from
+// PSM are dropped from the state so they will be created again on the next apply.
+func readNetworkSet(ctx context.Context, d *schema.ResourceData, config *Config, names map[string]bool) diag.Diagnostics {
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	current := map[string]*Network{}
+	for i := range networks {
+		current[networks[i].Meta.Name] = &networks[i]
+	}
+
+	members := []interface{}{}
+	for name := range names {
+		if network, ok := current[name]; ok {
+			members = append(members, flattenNetworkSetMember(network))
+		}
+	}
+
+	if err := d.Set("network", members); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceNetworkSetUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	if !d.HasChange("network") {
+		return resourceNetworkSetRead(ctx, d, m)
+	}
+
+	o, n := d.GetChange("network")
+	oldMembers := networkSetMembers(o)
+	newMembers := networkSetMembers(n)
+
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	current := map[string]*Network{}
+	for i := range networks {
+		current[networks[i].Meta.Name] = &networks[i]
+	}
+
+	// Members removed from the set or replaced are both deleted, so neither can still have workloads attached
+	removed := map[string]map[string]interface{}{}
+	for name, member := range oldMembers {
+		if _, ok := newMembers[name]; !ok {
+			removed[name] = member
+		}
+	}
+	for name, member := range newMembers {
+		network, exists := current[name]
+		if _, managed := oldMembers[name]; managed && exists && networkSetMemberReplaced(network, member) {
+			removed[name] = map[string]interface{}{"vlan_id": network.Spec.VlanID}
+		}
+	}
+	if diags := networkSetDependents(ctx, config, removed); diags.HasError() {
+		return diags
+	}
+
+	operations := []networkSetOperation{}
+
+	for name := range oldMembers {
+		if _, ok := newMembers[name]; !ok {
+			name := name
+			operations = append(operations, networkSetOperation{
+				name:   name,
+				action: "delete",
+				run: func() error {
+					return deleteNetwork(ctx, config, name)
+				},
+			})
+		}
+	}
+
+	for name, member := range newMembers {
+		network, exists := current[name]
+		_, managed := oldMembers[name]
+
+		// A network added to the set that already exists belongs to something else, so fail the same as a create
+		if !managed && exists {
+			name := name
+			operations = append(operations, networkSetOperation{
+				name:   name,
+				action: "create",
+				run: func() error {
+					return fmt.Errorf("network %s already exists in PSM, import it or remove it before adding it to the set", name)
+				},
+			})
+			continue
+		}
+
+		if !exists {
+			network = &Network{}
+			expandNetworkSetMember(member, network)
+			operations = append(operations, networkSetOperation{
+				name:   name,
+				action: "create",
+				run: func() error {
+					return sendNetwork(ctx, config, "POST", network)
+				},
+			})
+			continue
+		}
+
+		if oldMember, ok := oldMembers[name]; ok && reflect.DeepEqual(oldMember, member) {
+			continue
+		}
+
+		if networkSetMemberReplaced(network, member) {
+			replacement := &Network{}
+			expandNetworkSetMember(member, replacement)
+			operations = append(operations, networkSetOperation{
+				name:   name,
+				action: "replace",
+				run: func() error {
+					if err := deleteNetwork(ctx, config, replacement.Meta.Name); err != nil {
+						return err
+					}
+					return sendNetwork(ctx, config, "POST", replacement)
+				},
+			})
+			continue
+		}
+
+		expandNetworkSetMember(member, network)
+		operations = append(operations, networkSetOperation{
+			name:   name,
+			action: "update",
+			run: func() error {
+				return sendNetwork(ctx, config, "PUT", network)
+			},
+		})
+	}
+
+	// Networks that failed to be deleted are kept in the state so the delete is retried on the next apply, while
+	// networks that failed to be added are left out so one that already existed is never adopted
+	diags, failed := applyNetworkSetOperations(operations)
+	names := map[string]bool{}
+	for name := range newMembers {
+		if _, managed := oldMembers[name]; managed || !failed[name] {
+			names[name] = true
+		}
+	}
+	for name := range oldMembers {
+		if failed[name] {
+			names[name] = true
+		}
+	}
+
+	return append(diags, readNetworkSet(ctx, d, config, names)...)
+}
+
+// The VLAN and VRF of a network can't be changed in place so the network has to be replaced
+func networkSetMemberReplaced(network *Network, member map[string]interface{}) bool {
+	return network.Spec.VlanID != member["vlan_id"].(int) || network.Spec.VirtualRouter != member["vrf"].(string)
+}
+
+// Check none of the networks about to be deleted still have workloads attached, reporting every blocked network
+func networkSetDependents(ctx context.Context, config *Config, members map[string]map[string]interface{}) diag.Diagnostics {
+	if len(members) == 0 {
+		return nil
+	}
+
+	workloads, err := listWorkloads(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var diags diag.Diagnostics
+	for name, member := range members {
+		if blockers := networkDependents(workloads, name, member["vlan_id"].(int)); len(blockers) > 0 {
+			diags = append(diags, dependencyDiagnostics("network", name, blockers, "")...)
+		}
+	}
+
+	return diags
+}
+
+func resourceNetworkSetDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	if diags := networkSetDependents(ctx, config, networkSetMembers(d.Get("network"))); diags.HasError() {
+		return diags
+	}
+
+	operations := []networkSetOperation{}
+	for name := range networkSetMembers(d.Get("network")) {
+		name := name
+		operations = append(operations, networkSetOperation{
+			name:   name,
+			action: "delete",
+			run: func() error {
+				return deleteNetwork(ctx, config, name)
+			},
+		})
+	}
+
+	if diags, _ := applyNetworkSetOperations(operations); diags.HasError() {
+		return append(diags, resourceNetworkSetRead(ctx, d, m)...)
+	}
+
+	d.SetId("")
+
+	return nil
+}
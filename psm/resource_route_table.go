@@ -0,0 +1,228 @@
+package psm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Define the Terraform resource schema for the static routes of a VRF. PSM stores static routes on the virtual
+// router itself, so there is a single route table per VRF holding every static route configured within it.
+func resourceRouteTable() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceRouteTableCreate,
+		ReadContext:   resourceRouteTableRead,
+		UpdateContext: resourceRouteTableUpdate,
+		DeleteContext: resourceRouteTableDelete,
+		CustomizeDiff: resourceRouteTableCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"vrf": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"static_route": {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"prefix": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsCIDR,
+						},
+						"next_hop": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPAddress,
+						},
+						"admin_distance": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validation.IntBetween(1, 255),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type StaticRoute struct {
+	Prefix        string `json:"prefix"`
+	NextHop       string `json:"next-hop"`
+	AdminDistance int    `json:"admin-distance"`
+}
+
+// Check each route is self consistent at plan time. The VRF's address family is only known once it exists so that
+// check is made when the routes are applied.
+func resourceRouteTableCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("static_route") {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, v := range d.Get("static_route").(*schema.Set).List() {
+		route := v.(map[string]interface{})
+		prefix := route["prefix"].(string)
+		nextHop := route["next_hop"].(string)
+
+		// Values may still be unknown when they come from other resources
+		if prefix == "" || nextHop == "" {
+			continue
+		}
+
+		ip, network, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return fmt.Errorf("static_route prefix %q is not a valid CIDR", prefix)
+		}
+		if !ip.Equal(network.IP) {
+			return fmt.Errorf("static_route prefix %q has host bits set, did you mean %q?", prefix, network.String())
+		}
+
+		hop := net.ParseIP(nextHop)
+		if hop == nil {
+			return fmt.Errorf("static_route next_hop %q is not a valid IP address", nextHop)
+		}
+		if (ip.To4() == nil) != (hop.To4() == nil) {
+			return fmt.Errorf("static_route %s has next_hop %s from a different address family", prefix, nextHop)
+		}
+
+		key := network.String() + " via " + hop.String()
+		if seen[key] {
+			return fmt.Errorf("static_route %s is defined more than once", key)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// Build the PSM static routes from the resource, making sure they can be carried by the VRF's address family.
+// A VRF exchanging ipv4-unicast routes can't carry IPv6 prefixes, while an EVPN VRF carries both.
+func expandStaticRoutes(d *schema.ResourceData, vrf *VRF) ([]StaticRoute, error) {
+	ipv4Only := vrf.Spec.RouteImportExport != nil && vrf.Spec.RouteImportExport.AddressFamily == "ipv4-unicast"
+
+	routes := []StaticRoute{}
+	for _, v := range d.Get("static_route").(*schema.Set).List() {
+		route := v.(map[string]interface{})
+
+		_, network, err := net.ParseCIDR(route["prefix"].(string))
+		if err != nil {
+			return nil, err
+		}
+		if ipv4Only && network.IP.To4() == nil {
+			return nil, fmt.Errorf("VRF %q uses the ipv4-unicast address family and can't carry the IPv6 route %s", vrf.Meta.Name, network.String())
+		}
+
+		routes = append(routes, StaticRoute{
+			Prefix:        network.String(),
+			NextHop:       net.ParseIP(route["next_hop"].(string)).String(),
+			AdminDistance: route["admin_distance"].(int),
+		})
+	}
+
+	return routes, nil
+}
+
+func flattenStaticRoutes(routes []StaticRoute) []interface{} {
+	result := make([]interface{}, len(routes))
+	for i, route := range routes {
+		adminDistance := route.AdminDistance
+		if adminDistance == 0 {
+			adminDistance = 1
+		}
+		result[i] = map[string]interface{}{
+			"prefix":         route.Prefix,
+			"next_hop":       route.NextHop,
+			"admin_distance": adminDistance,
+		}
+	}
+	return result
+}
+
+// Replace the static routes on the VRF with the ones defined in the resource
+func applyRouteTable(ctx context.Context, d *schema.ResourceData, config *Config) diag.Diagnostics {
+	vrf, diags := getVRF(ctx, config, d.Get("vrf").(string))
+	if diags.HasError() {
+		return diags
+	}
+
+	routes, err := expandStaticRoutes(d, vrf)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	vrf.Spec.StaticRoutes = routes
+
+	return putVRF(ctx, config, vrf)
+}
+
+func resourceRouteTableCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	vrfName := d.Get("vrf").(string)
+
+	log.Printf("[DEBUG] Creating route table for VRF: %s", vrfName)
+
+	if diags := applyRouteTable(ctx, d, config); diags.HasError() {
+		return diags
+	}
+
+	d.SetId(vrfName)
+
+	return resourceRouteTableRead(ctx, d, m)
+}
+
+func resourceRouteTableRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	vrf, diags := getVRF(ctx, config, d.Id())
+	if diags.HasError() {
+		return diags
+	}
+
+	d.Set("vrf", vrf.Meta.Name)
+	if err := d.Set("static_route", flattenStaticRoutes(vrf.Spec.StaticRoutes)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceRouteTableUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	if d.HasChange("static_route") {
+		if diags := applyRouteTable(ctx, d, config); diags.HasError() {
+			return diags
+		}
+	}
+
+	return resourceRouteTableRead(ctx, d, m)
+}
+
+func resourceRouteTableDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	log.Printf("[DEBUG] Removing static routes from VRF: %s", d.Id())
+
+	vrf, diags := getVRF(ctx, config, d.Id())
+	if diags.HasError() {
+		return diags
+	}
+
+	vrf.Spec.StaticRoutes = nil
+	if diags := putVRF(ctx, config, vrf); diags.HasError() {
+		return diags
+	}
+
+	d.SetId("")
+
+	return nil
+}
@@ -21,6 +21,7 @@ func resourceRules() *schema.Resource {
 		ReadContext:   resourceRulesRead,
 		UpdateContext: resourceRulesUpdate,
 		DeleteContext: resourceRulesDelete,
+		CustomizeDiff: resourceRulesCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"policy_name": {
 				Type:     schema.TypeString,
@@ -39,6 +40,8 @@ func resourceRules() *schema.Resource {
 				Default:  "default",
 				ForceNew: true,
 			},
+			// Detach the policy from any networks and VRFs it is attached to when it is destroyed
+			"force_detach": forceDetachSchema(),
 			"meta": {
 				Type:     schema.TypeSet,
 				Computed: true,
@@ -136,6 +139,7 @@ func resourceRules() *schema.Resource {
 										Elem:     &schema.Schema{Type: schema.TypeString},
 										Computed: true,
 									},
+									"proto_ports": protoPortsComputedSchema(),
 								},
 							},
 						},
@@ -180,16 +184,9 @@ func resourceRules() *schema.Resource {
 							Elem:     &schema.Schema{Type: schema.TypeString},
 							Optional: true,
 						},
-						"from_ip_addresses": {
-							Type:     schema.TypeList,
-							Elem:     &schema.Schema{Type: schema.TypeString},
-							Optional: true,
-						},
-						"to_ip_addresses": {
-							Type:     schema.TypeList,
-							Elem:     &schema.Schema{Type: schema.TypeString},
-							Optional: true,
-						},
+						"from_ip_addresses": addressListSchema(),
+						"to_ip_addresses":   addressListSchema(),
+						"proto_ports":       protoPortsSchema(),
 						"apps": {
 							Type:     schema.TypeList,
 							Elem:     &schema.Schema{Type: schema.TypeString},
@@ -246,6 +243,7 @@ type Rule struct {
 	ToIPAddresses     []string    `json:"to-ip-addresses"`
 	FromIPCollections []string    `json:"from-ipcollections"`
 	ToIPCollections   []string    `json:"to-ipcollections"`
+	ProtoPorts        []ProtoPort `json:"proto-ports,omitempty"`
 }
 
 type Status struct {
@@ -314,10 +312,11 @@ func resourceRulesCreate(ctx context.Context, d *schema.ResourceData, m interfac
 				Action:            ruleMap["action"].(string),
 				Description:       ruleMap["description"].(string),
 				Name:              ruleMap["rule_name"].(string),
-				FromIPAddresses:   convertToStringSlice(ruleMap["from_ip_addresses"].([]interface{})),
-				ToIPAddresses:     convertToStringSlice(ruleMap["to_ip_addresses"].([]interface{})),
+				FromIPAddresses:   canonicalAddresses(convertToStringSlice(ruleMap["from_ip_addresses"].([]interface{}))),
+				ToIPAddresses:     canonicalAddresses(convertToStringSlice(ruleMap["to_ip_addresses"].([]interface{}))),
 				FromIPCollections: convertToStringSlice(ruleMap["from_ip_collections"].([]interface{})),
 				ToIPCollections:   convertToStringSlice(ruleMap["to_ip_collections"].([]interface{})),
+				ProtoPorts:        expandProtoPorts(ruleMap["proto_ports"]),
 			}
 			policy.Spec.Rules = append(policy.Spec.Rules, rule)
 		}
@@ -373,8 +372,9 @@ func resourceRulesCreate(ctx context.Context, d *schema.ResourceData, m interfac
 			"apps":                rule.Apps,
 			"from_ip_collections": rule.FromIPCollections,
 			"to_ip_collections":   rule.ToIPCollections,
-			"from_ip_addresses":   rule.FromIPAddresses,
-			"to_ip_addresses":     rule.ToIPAddresses,
+			"from_ip_addresses":   canonicalAddresses(rule.FromIPAddresses),
+			"to_ip_addresses":     canonicalAddresses(rule.ToIPAddresses),
+			"proto_ports":         flattenProtoPorts(rule.ProtoPorts),
 		}
 	}
 
@@ -451,8 +451,9 @@ func resourceRulesRead(ctx context.Context, d *schema.ResourceData, m interface{
 			"apps":                rule.Apps,
 			"from_ip_collections": rule.FromIPCollections,
 			"to_ip_collections":   rule.ToIPCollections,
-			"from_ip_addresses":   rule.FromIPAddresses,
-			"to_ip_addresses":     rule.ToIPAddresses,
+			"from_ip_addresses":   canonicalAddresses(rule.FromIPAddresses),
+			"to_ip_addresses":     canonicalAddresses(rule.ToIPAddresses),
+			"proto_ports":         flattenProtoPorts(rule.ProtoPorts),
 		}
 	}
 
@@ -487,6 +488,11 @@ func resourceRulesUpdate(ctx context.Context, d *schema.ResourceData, m interfac
 	client := config.Client()
 	policyName := d.Get("policy_name").(string)
 
+	// force_detach only matters when the policy is destroyed so there is nothing to send to PSM
+	if !d.HasChangeExcept("force_detach") {
+		return nil
+	}
+
 	// Create the GO Struct that we will populate with data from the resource to send to the PSM server eventually as JSON. If there is something
 	// not being sent to the  server correctly the ensure this structure is correct.
 	policy := &NetworkSecurityPolicy{
@@ -521,10 +527,11 @@ func resourceRulesUpdate(ctx context.Context, d *schema.ResourceData, m interfac
 				Action:            ruleMap["action"].(string),
 				Description:       ruleMap["description"].(string),
 				Name:              ruleMap["rule_name"].(string),
-				FromIPAddresses:   convertToStringSlice(ruleMap["from_ip_addresses"].([]interface{})),
-				ToIPAddresses:     convertToStringSlice(ruleMap["to_ip_addresses"].([]interface{})),
+				FromIPAddresses:   canonicalAddresses(convertToStringSlice(ruleMap["from_ip_addresses"].([]interface{}))),
+				ToIPAddresses:     canonicalAddresses(convertToStringSlice(ruleMap["to_ip_addresses"].([]interface{}))),
 				FromIPCollections: convertToStringSlice(ruleMap["from_ip_collections"].([]interface{})),
 				ToIPCollections:   convertToStringSlice(ruleMap["to_ip_collections"].([]interface{})),
+				ProtoPorts:        expandProtoPorts(ruleMap["proto_ports"]),
 			}
 			policy.Spec.Rules = append(policy.Spec.Rules, rule)
 		}
@@ -580,8 +587,9 @@ func resourceRulesUpdate(ctx context.Context, d *schema.ResourceData, m interfac
 			"apps":                rule.Apps,
 			"from_ip_collections": rule.FromIPCollections,
 			"to_ip_collections":   rule.ToIPCollections,
-			"from_ip_addresses":   rule.FromIPAddresses,
-			"to_ip_addresses":     rule.ToIPAddresses,
+			"from_ip_addresses":   canonicalAddresses(rule.FromIPAddresses),
+			"to_ip_addresses":     canonicalAddresses(rule.ToIPAddresses),
+			"proto_ports":         flattenProtoPorts(rule.ProtoPorts),
 		}
 	}
 
@@ -608,12 +616,121 @@ func resourceRulesUpdate(ctx context.Context, d *schema.ResourceData, m interfac
 	return nil
 }
 
+// PSM only rejects a rule whose from and to sides have no address family in common, or proto_ports settings that
+// don't apply to the protocol, when the policy is propagated, so check each rule when planning. A dual stack side
+// is fine as long as the other side shares one of its families. The family of a collection comes from PSM, so
+// collections that don't exist yet are skipped.
+func resourceRulesCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("rule") {
+		return nil
+	}
+	rules := d.Get("rule").([]interface{})
+
+	var byName map[string]IPCollection
+	for _, v := range rules {
+		rule, _ := v.(map[string]interface{})
+		if rule == nil {
+			continue
+		}
+		from, _ := rule["from_ip_collections"].([]interface{})
+		to, _ := rule["to_ip_collections"].([]interface{})
+		if m != nil && (len(from) > 0 || len(to) > 0) {
+			collections, err := listIPCollections(ctx, m.(*Config))
+			if err != nil {
+				return err
+			}
+			byName = ipCollectionsByName(collections)
+			break
+		}
+	}
+
+	for i, v := range rules {
+		rule, _ := v.(map[string]interface{})
+		if rule == nil {
+			continue
+		}
+
+		label, _ := rule["rule_name"].(string)
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		if err := validateRuleProtoPorts(rule, label); err != nil {
+			return err
+		}
+
+		from := ruleSideFamilies(rule, "from_ip_addresses", "from_ip_collections", byName)
+		to := ruleSideFamilies(rule, "to_ip_addresses", "to_ip_collections", byName)
+		if len(from) == 0 || len(to) == 0 || from["ipv4"] != "" && to["ipv4"] != "" || from["ipv6"] != "" && to["ipv6"] != "" {
+			continue
+		}
+
+		// With no family in common each side can only be a single, different, family
+		var fromFamily, toFamily string
+		for f := range from {
+			fromFamily = f
+		}
+		for f := range to {
+			toFamily = f
+		}
+		return fmt.Errorf("rule %q mixes IPv4 and IPv6: the from side is %s (%s) and the to side is %s (%s)",
+			label, fromFamily, from[fromFamily], toFamily, to[toFamily])
+	}
+
+	return nil
+}
+
+// The address families used on one side of a rule, each mapped to the first address or collection using it
+func ruleSideFamilies(rule map[string]interface{}, addressesKey, collectionsKey string, byName map[string]IPCollection) map[string]string {
+	families := map[string]string{}
+
+	addresses, _ := rule[addressesKey].([]interface{})
+	for _, address := range addresses {
+		s, _ := address.(string)
+		if f := addressFamily(s); f != "" && families[f] == "" {
+			families[f] = fmt.Sprintf("address %q", s)
+		}
+	}
+
+	names, _ := rule[collectionsKey].([]interface{})
+	for _, name := range names {
+		s, _ := name.(string)
+		collection, ok := byName[s]
+		if !ok {
+			continue
+		}
+		if f := ipCollectionAddressFamily(&collection, byName); f != "" && families[f] == "" {
+			families[f] = fmt.Sprintf("ip_collection %q", s)
+		}
+	}
+
+	return families
+}
+
 func resourceRulesDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	// Read the current configuration
 	config := m.(*Config)
 	client := config.Client()
 	policyName := d.Get("policy_name").(string)
 
+	// A policy still attached to a network or VRF can't be deleted, so either detach it or report what is using it
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	vrfs, err := listVRFs(ctx, config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if blockers := securityPolicyDependents(networks, vrfs, policyName); len(blockers) > 0 {
+		if !d.Get("force_detach").(bool) {
+			return dependencyDiagnostics("security policy", policyName, blockers,
+				"Set force_detach = true and apply before destroying to detach the policy automatically.")
+		}
+		if diags := detachSecurityPolicy(ctx, config, networks, vrfs, policyName); diags.HasError() {
+			return diags
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "DELETE", config.Server+"/configs/security/v1/tenant/default/networksecuritypolicies/"+policyName, nil)
 	if err != nil {
 		return diag.FromErr(err)
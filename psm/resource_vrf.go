@@ -7,35 +7,117 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// Upper bounds for the per network connection rate and session limits of a VRF
+const (
+	maxVRFCPSLimit      = 1000000
+	maxVRFSessionsLimit = 16777216
 )
 
 func resourceVRF() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceVRFCreate,
 		ReadContext:   resourceVRFRead,
+		UpdateContext: resourceVRFUpdate,
 		DeleteContext: resourceVRFDelete,
+		CustomizeDiff: resourceVRFCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:     schema.TypeString,
 				Required: true,
 				ForceNew: true,
 			},
-			"ingress_security_policy": {
+			"ingress_security_policy":   legacySecurityPolicySchema("ingress_security_policies"),
+			"egress_security_policy":    legacySecurityPolicySchema("egress_security_policies"),
+			"ingress_security_policies": securityPoliciesSchema("ingress_security_policy"),
+			"egress_security_policies":  securityPoliciesSchema("egress_security_policy"),
+			"default_ipam_policy": {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
-			"egress_security_policy": {
+			"route_import_export": routeImportExportSchema(false),
+			"type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"tenant", "infra"}, false),
+			},
+			"vxlan_vni": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(1, 16777215),
+			},
+			"router_mac_address": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateFunc:     validateMACAddress,
+				DiffSuppressFunc: suppressEquivalentMACAddress,
+			},
+			"ingress_nat_policies": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"egress_nat_policies": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"ipsec_policies": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"flow_export_policies": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			// The values the default VRF had before Terraform took over its attributes, restored when it is destroyed
+			"adopted_spec": {
 				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"limits": {
+				Type:     schema.TypeList,
 				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"max_cps_per_network": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validation.IntBetween(0, maxVRFCPSLimit),
+						},
+						"max_sessions_per_network": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validation.IntBetween(0, maxVRFSessionsLimit),
+						},
+					},
+				},
 			},
 		},
 	}
 }
 
+type VRFList struct {
+	Kind       interface{} `json:"kind"`
+	APIVersion interface{} `json:"api-version"`
+	ListMeta   interface{} `json:"list-meta"`
+	Items      []VRF       `json:"items"`
+}
+
 type VRF struct {
 	Kind       interface{} `json:"kind"`
 	APIVersion interface{} `json:"api-version"`
@@ -65,9 +147,388 @@ type VRF struct {
 		IpsecPolicy                                           []interface{} `json:"ipsec-policy"`
 		SelectCPS                                             int           `json:"selectCPS"`
 		SelectSessions                                        int           `json:"selectSessions"`
+		RouteImportExport                                     *RDSpec       `json:"route-import-export"`
+		StaticRoutes                                          []StaticRoute `json:"static-routes"`
 	} `json:"spec"`
 }
 
+// Apply all of the configurable attributes of the VRF from the resource definition
+func expandVRF(d *schema.ResourceData, vrf *VRF) error {
+	vrf.Spec.IngressSecurityPolicy = expandSecurityPolicies(d, "ingress_security_policies", "ingress_security_policy")
+	vrf.Spec.EgressSecurityPolicy = expandSecurityPolicies(d, "egress_security_policies", "egress_security_policy")
+
+	vrf.Spec.DefaultIpamPolicy = nil
+	if v, ok := d.GetOk("default_ipam_policy"); ok {
+		vrf.Spec.DefaultIpamPolicy = v.(string)
+	}
+
+	routeImportExport, err := expandRouteImportExport(d.Get("route_import_export").([]interface{}))
+	if err != nil {
+		return err
+	}
+	vrf.Spec.RouteImportExport = routeImportExport
+
+	if v, ok := d.GetOk("type"); ok {
+		vrf.Spec.Type = v.(string)
+	}
+
+	vrf.Spec.VxlanVni = nil
+	if v, ok := d.GetOk("vxlan_vni"); ok {
+		vrf.Spec.VxlanVni = v.(int)
+	}
+
+	vrf.Spec.RouterMacAddress = nil
+	if v, ok := d.GetOk("router_mac_address"); ok {
+		vrf.Spec.RouterMacAddress = formatMACAddress(v.(string))
+	}
+
+	expandVRFLimits(d.Get("limits").([]interface{}), vrf)
+
+	vrf.Spec.FlowExportPolicy = nil
+	if v, ok := d.GetOk("flow_export_policies"); ok {
+		vrf.Spec.FlowExportPolicy = v.([]interface{})
+	}
+
+	vrf.Spec.IngressNatPolicy = nil
+	if v, ok := d.GetOk("ingress_nat_policies"); ok {
+		vrf.Spec.IngressNatPolicy = v.([]interface{})
+	}
+
+	vrf.Spec.EgressNatPolicy = nil
+	if v, ok := d.GetOk("egress_nat_policies"); ok {
+		vrf.Spec.EgressNatPolicy = v.([]interface{})
+	}
+
+	vrf.Spec.IpsecPolicy = nil
+	if v, ok := d.GetOk("ipsec_policies"); ok {
+		vrf.Spec.IpsecPolicy = v.([]interface{})
+	}
+
+	return nil
+}
+
+// Set the per network CPS and session limits. The select fields tell PSM whether a limit is in use, so they are
+// only enabled when the limit is not 0.
+func expandVRFLimits(v []interface{}, vrf *VRF) {
+	cps, sessions := 0, 0
+	if len(v) > 0 && v[0] != nil {
+		limits := v[0].(map[string]interface{})
+		cps = limits["max_cps_per_network"].(int)
+		sessions = limits["max_sessions_per_network"].(int)
+	}
+
+	vrf.Spec.MaximumCpsPerNetworkPerDistributedServicesEntity = cps
+	vrf.Spec.SelectCPS = 0
+	if cps > 0 {
+		vrf.Spec.SelectCPS = 1
+	}
+
+	vrf.Spec.MaximumSessionsPerNetworkPerDistributedServicesEntity = sessions
+	vrf.Spec.SelectSessions = 0
+	if sessions > 0 {
+		vrf.Spec.SelectSessions = 1
+	}
+}
+
+// Read the limits back from PSM. No limits looks the same as an empty limits block, so the block is kept whenever
+// it's already in the configuration or state to avoid a diff on every plan.
+func flattenVRFLimits(vrf *VRF, present bool) []interface{} {
+	cps, sessions := 0, 0
+	if vrf.Spec.SelectCPS != 0 {
+		cps = vrf.Spec.MaximumCpsPerNetworkPerDistributedServicesEntity
+	}
+	if vrf.Spec.SelectSessions != 0 {
+		sessions = vrf.Spec.MaximumSessionsPerNetworkPerDistributedServicesEntity
+	}
+	if cps == 0 && sessions == 0 && !present {
+		return []interface{}{}
+	}
+	return []interface{}{map[string]interface{}{
+		"max_cps_per_network":      cps,
+		"max_sessions_per_network": sessions,
+	}}
+}
+
+// The fields of the PSM VRF spec set by each attribute of the resource
+var vrfSpecFields = map[string][]string{
+	"ingress_security_policy":   {"ingress-security-policy"},
+	"ingress_security_policies": {"ingress-security-policy"},
+	"egress_security_policy":    {"egress-security-policy"},
+	"egress_security_policies":  {"egress-security-policy"},
+	"default_ipam_policy":       {"default-ipam-policy"},
+	"route_import_export":       {"route-import-export"},
+	"type":                      {"type"},
+	"vxlan_vni":                 {"vxlan-vni"},
+	"router_mac_address":        {"router-mac-address"},
+	"ingress_nat_policies":      {"ingress-nat-policy"},
+	"egress_nat_policies":       {"egress-nat-policy"},
+	"ipsec_policies":            {"ipsec-policy"},
+	"flow_export_policies":      {"flow-export-policy"},
+	"limits": {
+		"maximum-cps-per-network-per-distributed-services-entity",
+		"maximum-sessions-per-network-per-distributed-services-entity",
+		"selectCPS",
+		"selectSessions",
+	},
+}
+
+// The raw JSON of the given fields of the VRF spec, skipping any already in values
+func captureVRFSpec(vrf *VRF, fields []string, values map[string]json.RawMessage) error {
+	jsonBytes, err := json.Marshal(vrf.Spec)
+	if err != nil {
+		return err
+	}
+	spec := map[string]json.RawMessage{}
+	if err := json.Unmarshal(jsonBytes, &spec); err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if _, ok := values[field]; !ok {
+			values[field] = spec[field]
+		}
+	}
+	return nil
+}
+
+// Overwrite only the given fields of the VRF spec, leaving the rest as they are
+func restoreVRFSpec(vrf *VRF, values map[string]json.RawMessage) error {
+	jsonBytes, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonBytes, &vrf.Spec)
+}
+
+// The original values of the default VRF recorded in adopted_spec
+func adoptedVRFSpec(d *schema.ResourceData) (map[string]json.RawMessage, error) {
+	values := map[string]json.RawMessage{}
+	if v := d.Get("adopted_spec").(string); v != "" {
+		if err := json.Unmarshal([]byte(v), &values); err != nil {
+			return nil, fmt.Errorf("invalid adopted_spec: %s", err)
+		}
+	}
+	return values, nil
+}
+
+// Record the current values of the fields of the default VRF about to be changed for the first time
+func captureAdoptedVRFSpec(d *schema.ResourceData, vrf *VRF, attributes []string) error {
+	values, err := adoptedVRFSpec(d)
+	if err != nil {
+		return err
+	}
+	for _, attribute := range attributes {
+		if err := captureVRFSpec(vrf, vrfSpecFields[attribute], values); err != nil {
+			return err
+		}
+	}
+
+	jsonBytes, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return d.Set("adopted_spec", string(jsonBytes))
+}
+
+func validateMACAddress(v interface{}, k string) ([]string, []error) {
+	mac, err := net.ParseMAC(v.(string))
+	if err != nil || len(mac) != 6 {
+		return nil, []error{fmt.Errorf("%q must be a valid MAC address such as 00ae.cd01.0203 or 00:ae:cd:01:02:03, got %q", k, v.(string))}
+	}
+	return nil, nil
+}
+
+// PSM expects MAC addresses in the aabb.ccdd.eeff notation
+func formatMACAddress(value string) string {
+	mac, err := net.ParseMAC(value)
+	if err != nil || len(mac) != 6 {
+		return value
+	}
+	return fmt.Sprintf("%02x%02x.%02x%02x.%02x%02x", mac[0], mac[1], mac[2], mac[3], mac[4], mac[5])
+}
+
+func suppressEquivalentMACAddress(k, old, new string, d *schema.ResourceData) bool {
+	return formatMACAddress(old) == formatMACAddress(new)
+}
+
+// Check the VXLAN VNI being configured isn't already used by another VRF. This is only checked when the VNI
+// changes as it requires reading every virtual router from PSM.
+func resourceVRFCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if m == nil || !d.HasChange("vxlan_vni") || !d.NewValueKnown("vxlan_vni") {
+		return nil
+	}
+
+	vni := d.Get("vxlan_vni").(int)
+	if vni == 0 {
+		return nil
+	}
+
+	vrfs, err := listVRFs(ctx, m.(*Config))
+	if err != nil {
+		return err
+	}
+
+	for _, vrf := range vrfs {
+		if vrf.Meta.Name != d.Get("name").(string) && interfaceToInt(vrf.Spec.VxlanVni) == vni {
+			return fmt.Errorf("vxlan_vni %d is already used by VRF %q", vni, vrf.Meta.Name)
+		}
+	}
+
+	return nil
+}
+
+// Retrieve all of the virtual routers in the tenant with a single call
+func listVRFs(ctx context.Context, config *Config) ([]VRF, error) {
+	client := config.Client()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", config.Server+"/configs/network/v1/tenant/default/virtualrouters", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list VRFs: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+	}
+
+	vrfs := &VRFList{}
+	if err := json.NewDecoder(resp.Body).Decode(vrfs); err != nil {
+		return nil, err
+	}
+
+	return vrfs.Items, nil
+}
+
+// Retrieve the current virtual router from PSM so it can be modified and sent back with putVRF
+func getVRF(ctx context.Context, config *Config, name string) (*VRF, diag.Diagnostics) {
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/virtualrouters/" + name
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] Error getting current VRF state: %s", err)
+		return nil, diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, diag.Errorf("failed to get current VRF state: HTTP %s", resp.Status)
+	}
+
+	vrf := &VRF{}
+	if err := json.NewDecoder(resp.Body).Decode(vrf); err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	return vrf, nil
+}
+
+func putVRF(ctx context.Context, config *Config, vrf *VRF) diag.Diagnostics {
+	client := config.Client()
+
+	url := config.Server + "/configs/network/v1/tenant/default/virtualrouters/" + vrf.Meta.Name
+
+	jsonBytes, err := json.Marshal(vrf)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "sid", Value: config.SID})
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("[ERROR] Error when updating VRF: %s", err)
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		errMsg := fmt.Sprintf("failed to update VRF: HTTP %d %s: %s", resp.StatusCode, resp.Status, bodyBytes)
+		return diag.Diagnostics{
+			{
+				Severity: diag.Error,
+				Summary:  "VRF update failed",
+				Detail:   errMsg,
+			},
+		}
+	}
+
+	return nil
+}
+
+// Take over management of the default VRF, applying the configured attributes to the existing virtual router
+func resourceVRFAdoptDefault(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	log.Printf("[DEBUG] Adopting the default VRF")
+
+	vrf, diags := getVRF(ctx, config, "default")
+	if diags.HasError() {
+		return diags
+	}
+
+	// Only the attributes in the configuration are taken over, everything else on the default VRF is left as it is
+	attributes := []string{}
+	fields := []string{}
+	rawConfig := d.GetRawConfig()
+	for attribute := range vrfSpecFields {
+		v := rawConfig.GetAttr(attribute)
+		if v.IsNull() || !v.IsKnown() || (v.Type().IsListType() && v.LengthInt() == 0) {
+			continue
+		}
+		attributes = append(attributes, attribute)
+		fields = append(fields, vrfSpecFields[attribute]...)
+	}
+
+	if err := captureAdoptedVRFSpec(d, vrf, attributes); err != nil {
+		return diag.FromErr(err)
+	}
+
+	desired := &VRF{}
+	if err := expandVRF(d, desired); err != nil {
+		return diag.FromErr(err)
+	}
+	values := map[string]json.RawMessage{}
+	if err := captureVRFSpec(desired, fields, values); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := restoreVRFSpec(vrf, values); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if diags := putVRF(ctx, config, vrf); diags.HasError() {
+		return diags
+	}
+
+	d.SetId(interfaceToString(vrf.Meta.UUID))
+	if d.Id() == "" {
+		d.SetId("default")
+	}
+
+	return resourceVRFRead(ctx, d, m)
+}
+
 func resourceVRFCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	config := m.(*Config)
 	client := config.Client()
@@ -77,15 +538,15 @@ func resourceVRFCreate(ctx context.Context, d *schema.ResourceData, m interface{
 	vrf.Meta.Tenant = "default"
 	vrf.Spec.Type = "unknown"
 	vrfName := d.Get("name").(string)
-	if v, ok := d.GetOk("ingress_security_policy"); ok {
-		vrf.Spec.IngressSecurityPolicy = []interface{}{v.(string)}
-	}
-	if v, ok := d.GetOk("egress_security_policy"); ok {
-		vrf.Spec.EgressSecurityPolicy = []interface{}{v.(string)}
-	}
+
+	// The default VRF always exists in PSM and can't be created, so adopt it by applying the configured attributes
+	// to the existing virtual router instead.
 	if vrfName == "default" {
-		d.SetId("default")
-		return nil
+		return resourceVRFAdoptDefault(ctx, d, m)
+	}
+
+	if err := expandVRF(d, vrf); err != nil {
+		return diag.FromErr(err)
 	}
 
 	jsonBytes, err := json.Marshal(vrf)
@@ -168,20 +629,200 @@ func resourceVRFRead(ctx context.Context, d *schema.ResourceData, m interface{})
 	d.Set("name", vrf.Meta.Name)
 	d.Set("kind", vrf.Kind)
 	d.Set("api_version", vrf.APIVersion)
+	if ipamPolicy, ok := vrf.Spec.DefaultIpamPolicy.(string); ok {
+		d.Set("default_ipam_policy", ipamPolicy)
+	} else {
+		d.Set("default_ipam_policy", "")
+	}
+	if err := d.Set("route_import_export", flattenRouteImportExport(vrf.Spec.RouteImportExport)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := flattenSecurityPolicies(d, "ingress_security_policies", "ingress_security_policy", vrf.Spec.IngressSecurityPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := flattenSecurityPolicies(d, "egress_security_policies", "egress_security_policy", vrf.Spec.EgressSecurityPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	d.Set("type", vrf.Spec.Type)
+	d.Set("vxlan_vni", interfaceToInt(vrf.Spec.VxlanVni))
+	d.Set("router_mac_address", interfaceToString(vrf.Spec.RouterMacAddress))
+	if err := d.Set("limits", flattenVRFLimits(vrf, len(d.Get("limits").([]interface{})) > 0)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("flow_export_policies", vrf.Spec.FlowExportPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("ingress_nat_policies", vrf.Spec.IngressNatPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("egress_nat_policies", vrf.Spec.EgressNatPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("ipsec_policies", vrf.Spec.IpsecPolicy); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Attributes of the default VRF Terraform hasn't taken over are left out, so they aren't planned for removal
+	if vrf.Meta.Name == "default" {
+		values, err := adoptedVRFSpec(d)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		for attribute, fields := range vrfSpecFields {
+			if _, ok := values[fields[0]]; !ok {
+				d.Set(attribute, nil)
+			}
+		}
+	}
 
 	return nil
 }
 
+// Update the VRF by reading the current virtual router from PSM and only changing the attributes that have changed
+// in the configuration, so that anything configured outside of Terraform is left in place.
+func resourceVRFUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+
+	log.Printf("[DEBUG] Updating VRF with name: %s", d.Get("name").(string))
+
+	vrfCurrent, diags := getVRF(ctx, config, d.Get("name").(string))
+	if diags.HasError() {
+		return diags
+	}
+
+	// Keep the original value of any attribute of the default VRF Terraform starts managing, so it can be restored
+	if d.Get("name").(string) == "default" {
+		changed := []string{}
+		for attribute := range vrfSpecFields {
+			if d.HasChange(attribute) {
+				changed = append(changed, attribute)
+			}
+		}
+		if err := captureAdoptedVRFSpec(d, vrfCurrent, changed); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChanges("ingress_security_policy", "ingress_security_policies") {
+		vrfCurrent.Spec.IngressSecurityPolicy = expandSecurityPolicies(d, "ingress_security_policies", "ingress_security_policy")
+	}
+
+	if d.HasChanges("egress_security_policy", "egress_security_policies") {
+		vrfCurrent.Spec.EgressSecurityPolicy = expandSecurityPolicies(d, "egress_security_policies", "egress_security_policy")
+	}
+
+	if d.HasChange("default_ipam_policy") {
+		if val, ok := d.GetOk("default_ipam_policy"); ok {
+			vrfCurrent.Spec.DefaultIpamPolicy = val.(string)
+		} else {
+			vrfCurrent.Spec.DefaultIpamPolicy = nil
+		}
+	}
+
+	if d.HasChange("route_import_export") {
+		routeImportExport, err := expandRouteImportExport(d.Get("route_import_export").([]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		vrfCurrent.Spec.RouteImportExport = routeImportExport
+	}
+
+	if d.HasChange("vxlan_vni") {
+		if val, ok := d.GetOk("vxlan_vni"); ok {
+			vrfCurrent.Spec.VxlanVni = val.(int)
+		} else {
+			vrfCurrent.Spec.VxlanVni = nil
+		}
+	}
+
+	if d.HasChange("router_mac_address") {
+		if val, ok := d.GetOk("router_mac_address"); ok {
+			vrfCurrent.Spec.RouterMacAddress = formatMACAddress(val.(string))
+		} else {
+			vrfCurrent.Spec.RouterMacAddress = nil
+		}
+	}
+
+	if d.HasChange("limits") {
+		expandVRFLimits(d.Get("limits").([]interface{}), vrfCurrent)
+	}
+
+	if d.HasChange("flow_export_policies") {
+		if val, ok := d.GetOk("flow_export_policies"); ok {
+			vrfCurrent.Spec.FlowExportPolicy = val.([]interface{})
+		} else {
+			vrfCurrent.Spec.FlowExportPolicy = nil
+		}
+	}
+
+	if d.HasChange("ingress_nat_policies") {
+		if val, ok := d.GetOk("ingress_nat_policies"); ok {
+			vrfCurrent.Spec.IngressNatPolicy = val.([]interface{})
+		} else {
+			vrfCurrent.Spec.IngressNatPolicy = nil
+		}
+	}
+
+	if d.HasChange("egress_nat_policies") {
+		if val, ok := d.GetOk("egress_nat_policies"); ok {
+			vrfCurrent.Spec.EgressNatPolicy = val.([]interface{})
+		} else {
+			vrfCurrent.Spec.EgressNatPolicy = nil
+		}
+	}
+
+	if d.HasChange("ipsec_policies") {
+		if val, ok := d.GetOk("ipsec_policies"); ok {
+			vrfCurrent.Spec.IpsecPolicy = val.([]interface{})
+		} else {
+			vrfCurrent.Spec.IpsecPolicy = nil
+		}
+	}
+
+	if diags := putVRF(ctx, config, vrfCurrent); diags.HasError() {
+		return diags
+	}
+
+	return resourceVRFRead(ctx, d, m)
+}
+
 func resourceVRFDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	config := m.(*Config)
 	client := config.Client()
 	vrfName := d.Get("name").(string)
 
+	// The default VRF can't be deleted, so put back the values the attributes Terraform took over had before
 	if vrfName == "default" {
-		d.SetId("default")
+		values, err := adoptedVRFSpec(d)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if len(values) > 0 {
+			vrf, diags := getVRF(ctx, config, vrfName)
+			if diags.HasError() {
+				return diags
+			}
+			if err := restoreVRFSpec(vrf, values); err != nil {
+				return diag.FromErr(err)
+			}
+			if diags := putVRF(ctx, config, vrf); diags.HasError() {
+				return diags
+			}
+		}
+
+		d.SetId("")
 		return nil
 	}
 
+	// PSM refuses to delete a VRF that still has networks in it, so list them up front rather than failing on the DELETE
+	networks, err := listNetworks(ctx, config, "")
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if blockers := vrfDependents(networks, vrfName); len(blockers) > 0 {
+		return dependencyDiagnostics("VRF", vrfName, blockers, "")
+	}
+
 	url := config.Server + "/configs/network/v1/tenant/default/virtualrouters/" + d.Get("name").(string)
 
 	log.Printf("[DEBUG] Deleting VRF with name: %s", d.Get("name").(string))
@@ -200,7 +841,7 @@ func resourceVRFDelete(ctx context.Context, d *schema.ResourceData, m interface{
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		return diag.Errorf("failed to delete VRF: HTTP %s", resp.Status)
 	}
 
@@ -0,0 +1,248 @@
+package psm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// RDSpec is the EVPN route distinguisher and route target configuration shared by networks and VRFs.
+type RDSpec struct {
+	AddressFamily string               `json:"address-family"`
+	RDAuto        bool                 `json:"rd-auto"`
+	RD            *RouteDistinguisher  `json:"rd"`
+	ExportRTs     []RouteDistinguisher `json:"export-rts"`
+	ImportRTs     []RouteDistinguisher `json:"import-rts"`
+}
+
+// RouteDistinguisher holds a route distinguisher or route target in the PSM encoding. type0 is a 2 byte ASN with a
+// 4 byte assigned value, type1 an IPv4 address with a 2 byte assigned value and type2 a 4 byte ASN with a 2 byte
+// assigned value.
+type RouteDistinguisher struct {
+	Type          string `json:"type"`
+	AdminValue    uint32 `json:"admin-value"`
+	AssignedValue uint32 `json:"assigned-value"`
+}
+
+// Schema for the route_import_export block used by both psm_network and psm_vrf. The route distinguisher and
+// route targets are written the same way they would be on a switch, either ASN:NN or IP:NN.
+func routeImportExportSchema(forceNew bool) *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		ForceNew: forceNew,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"address_family": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "l2vpn-evpn",
+					ValidateFunc: validation.StringInSlice([]string{"l2vpn-evpn", "ipv4-unicast"}, false),
+				},
+				"rd_auto": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  false,
+				},
+				"rd": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Computed:     true,
+					ValidateFunc: validateRouteDistinguisher,
+				},
+				"import_route_targets": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validateRouteDistinguisher,
+					},
+				},
+				"export_route_targets": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validateRouteDistinguisher,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Schema for the route_import_export block returned by the data sources
+func routeImportExportDataSourceSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"address_family": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"rd_auto": {
+					Type:     schema.TypeBool,
+					Computed: true,
+				},
+				"rd": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"import_route_targets": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+				"export_route_targets": {
+					Type:     schema.TypeList,
+					Computed: true,
+					Elem:     &schema.Schema{Type: schema.TypeString},
+				},
+			},
+		},
+	}
+}
+
+// Validate a route distinguisher or route target is in either the ASN:NN or IP:NN format
+func validateRouteDistinguisher(v interface{}, k string) ([]string, []error) {
+	if _, err := parseRouteDistinguisher(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%q: %s", k, err)}
+	}
+	return nil, nil
+}
+
+func parseRouteDistinguisher(value string) (*RouteDistinguisher, error) {
+	i := strings.LastIndex(value, ":")
+	if i <= 0 || i == len(value)-1 {
+		return nil, fmt.Errorf("%q must be in the format ASN:NN or IP:NN", value)
+	}
+	admin, assigned := value[:i], value[i+1:]
+
+	assignedValue, err := strconv.ParseUint(assigned, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%q has an invalid assigned number %q", value, assigned)
+	}
+
+	if ip := net.ParseIP(admin); ip != nil {
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("%q must use an IPv4 address", value)
+		}
+		if assignedValue > math.MaxUint16 {
+			return nil, fmt.Errorf("%q assigned number must be between 0 and %d when using IP:NN", value, math.MaxUint16)
+		}
+		return &RouteDistinguisher{
+			Type:          "type1",
+			AdminValue:    binary.BigEndian.Uint32(ip.To4()),
+			AssignedValue: uint32(assignedValue),
+		}, nil
+	}
+
+	asn, err := strconv.ParseUint(admin, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%q has an invalid ASN or IPv4 address %q", value, admin)
+	}
+	if asn <= math.MaxUint16 {
+		return &RouteDistinguisher{
+			Type:          "type0",
+			AdminValue:    uint32(asn),
+			AssignedValue: uint32(assignedValue),
+		}, nil
+	}
+	if assignedValue > math.MaxUint16 {
+		return nil, fmt.Errorf("%q assigned number must be between 0 and %d when using a 4 byte ASN", value, math.MaxUint16)
+	}
+	return &RouteDistinguisher{
+		Type:          "type2",
+		AdminValue:    uint32(asn),
+		AssignedValue: uint32(assignedValue),
+	}, nil
+}
+
+func formatRouteDistinguisher(rd RouteDistinguisher) string {
+	if rd.Type == "type1" {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, rd.AdminValue)
+		return fmt.Sprintf("%s:%d", ip.String(), rd.AssignedValue)
+	}
+	return fmt.Sprintf("%d:%d", rd.AdminValue, rd.AssignedValue)
+}
+
+// Build the PSM route-import-export object from the route_import_export block, returning nil if it isn't defined
+func expandRouteImportExport(v []interface{}) (*RDSpec, error) {
+	if len(v) == 0 || v[0] == nil {
+		return nil, nil
+	}
+	block := v[0].(map[string]interface{})
+
+	spec := &RDSpec{
+		AddressFamily: block["address_family"].(string),
+		RDAuto:        block["rd_auto"].(bool),
+		ExportRTs:     []RouteDistinguisher{},
+		ImportRTs:     []RouteDistinguisher{},
+	}
+
+	if rd := block["rd"].(string); rd != "" && !spec.RDAuto {
+		parsed, err := parseRouteDistinguisher(rd)
+		if err != nil {
+			return nil, err
+		}
+		spec.RD = parsed
+	}
+
+	for _, rt := range block["import_route_targets"].([]interface{}) {
+		parsed, err := parseRouteDistinguisher(rt.(string))
+		if err != nil {
+			return nil, err
+		}
+		spec.ImportRTs = append(spec.ImportRTs, *parsed)
+	}
+
+	for _, rt := range block["export_route_targets"].([]interface{}) {
+		parsed, err := parseRouteDistinguisher(rt.(string))
+		if err != nil {
+			return nil, err
+		}
+		spec.ExportRTs = append(spec.ExportRTs, *parsed)
+	}
+
+	return spec, nil
+}
+
+// Convert the PSM route-import-export object back into the route_import_export block stored in state
+func flattenRouteImportExport(spec *RDSpec) []interface{} {
+	if spec == nil {
+		return []interface{}{}
+	}
+
+	rd := ""
+	if spec.RD != nil {
+		rd = formatRouteDistinguisher(*spec.RD)
+	}
+
+	importRTs := make([]interface{}, len(spec.ImportRTs))
+	for i, rt := range spec.ImportRTs {
+		importRTs[i] = formatRouteDistinguisher(rt)
+	}
+
+	exportRTs := make([]interface{}, len(spec.ExportRTs))
+	for i, rt := range spec.ExportRTs {
+		exportRTs[i] = formatRouteDistinguisher(rt)
+	}
+
+	return []interface{}{map[string]interface{}{
+		"address_family":       spec.AddressFamily,
+		"rd_auto":              spec.RDAuto,
+		"rd":                   rd,
+		"import_route_targets": importRTs,
+		"export_route_targets": exportRTs,
+	}}
+}
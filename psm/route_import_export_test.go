@@ -0,0 +1,48 @@
+package psm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRouteDistinguisher(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    *RouteDistinguisher
+		wantErr bool
+	}{
+		{input: "65000:100", want: &RouteDistinguisher{Type: "type0", AdminValue: 65000, AssignedValue: 100}},
+		{input: "65535:4294967295", want: &RouteDistinguisher{Type: "type0", AdminValue: 65535, AssignedValue: 4294967295}},
+		{input: "10.1.1.1:100", want: &RouteDistinguisher{Type: "type1", AdminValue: 0x0a010101, AssignedValue: 100}},
+		{input: "4200000000:100", want: &RouteDistinguisher{Type: "type2", AdminValue: 4200000000, AssignedValue: 100}},
+		{input: "10.1.1.1:65536", wantErr: true},
+		{input: "4200000000:65536", wantErr: true},
+		{input: "65000:4294967296", wantErr: true},
+		{input: "4294967296:1", wantErr: true},
+		{input: "2001:db8::1:100", wantErr: true},
+		{input: "65000", wantErr: true},
+		{input: ":100", wantErr: true},
+		{input: "65000:", wantErr: true},
+		{input: "asn:100", wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := parseRouteDistinguisher(c.input)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseRouteDistinguisher(%q) = %+v, want an error", c.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRouteDistinguisher(%q) returned error: %s", c.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parseRouteDistinguisher(%q) = %+v, want %+v", c.input, got, c.want)
+		}
+		if formatted := formatRouteDistinguisher(*got); formatted != c.input {
+			t.Errorf("formatRouteDistinguisher(%+v) = %q, want %q", got, formatted, c.input)
+		}
+	}
+}
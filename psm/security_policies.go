@@ -0,0 +1,53 @@
+package psm
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Schema for the ordered list of security policies attached to a network or VRF in a single direction. The
+// single policy attribute is kept for existing configurations but can't be combined with the list.
+func securityPoliciesSchema(legacyKey string) *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeList,
+		Optional:      true,
+		Elem:          &schema.Schema{Type: schema.TypeString},
+		ConflictsWith: []string{legacyKey},
+	}
+}
+
+func legacySecurityPolicySchema(listKey string) *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		Deprecated:    "Use " + listKey + " instead, which allows more than one policy to be attached",
+		ConflictsWith: []string{listKey},
+	}
+}
+
+// Build the list of attached policies in the order they are defined, using either the list attribute or the
+// deprecated single policy attribute. A nil result detaches all policies.
+func expandSecurityPolicies(d *schema.ResourceData, listKey, legacyKey string) []interface{} {
+	if v, ok := d.GetOk(legacyKey); ok {
+		return []interface{}{v.(string)}
+	}
+	if v, ok := d.GetOk(listKey); ok {
+		return v.([]interface{})
+	}
+	return nil
+}
+
+// Store the attached policies in whichever attribute the configuration is using so that a policy attached or
+// detached outside of Terraform shows up as drift.
+func flattenSecurityPolicies(d *schema.ResourceData, listKey, legacyKey string, policies []interface{}) error {
+	if _, ok := d.GetOk(legacyKey); ok {
+		policy := ""
+		if len(policies) == 1 {
+			policy, _ = policies[0].(string)
+		}
+		return d.Set(legacyKey, policy)
+	}
+	if policies == nil {
+		policies = []interface{}{}
+	}
+	return d.Set(listKey, policies)
+}